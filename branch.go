@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// defaultBranch is used when no branch has been chosen.
+const defaultBranch = "production"
+
+// Branch is a launcher release channel listed by the metadata server.
+type Branch struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// unknownBranchError is returned when the requested branch isn't
+// offered by the metadata server.
+type unknownBranchError struct {
+	branch    string
+	available []string
+}
+
+func (e *unknownBranchError) Error() string {
+	return fmt.Sprintf("branch %q does not exist, available branches are: %s",
+		e.branch, strings.Join(e.available, ", "))
+}
+
+// fetchBranches lists the branches offered by the metadata server,
+// including the private ones the current access token unlocks.
+func (p *Pinnacle) fetchBranches(ctx context.Context) ([]Branch, error) {
+	url := MetadataURL + "/branches"
+	resp, err := p.request(ctx, url, p.authHeader(url), http.StatusOK)
+	if err != nil {
+		return nil, p.branchAccess(err)
+	}
+	defer func() {
+		p.CaptureErr(ctx, resp.Body.Close())
+	}()
+
+	if err = checkCaptivePortal(url, resp, true); err != nil {
+		return nil, err
+	}
+
+	var branches []Branch
+	if err = json.NewDecoder(resp.Body).Decode(&branches); err != nil {
+		return nil, fmt.Errorf("invalid branch list from %s: %w", url, err)
+	}
+	return branches, nil
+}
+
+// checkBranch makes sure p.branch is offered by the metadata server.
+// A branch chosen on the command line or in the environment must exist,
+// while a remembered branch that has since been removed falls back to
+// the default branch for this run. Servers without a branch list aren't checked.
+func (p *Pinnacle) checkBranch(ctx context.Context) error {
+	branches, err := p.fetchBranches(ctx)
+	if errors.Is(err, errNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	available := make([]string, 0, len(branches))
+	for _, b := range branches {
+		if b.Name == p.branch {
+			return nil
+		}
+		available = append(available, b.Name)
+	}
+
+	// A private branch is only listed for a token that grants access.
+	if p.token != "" {
+		return &branchAccessError{branch: p.branch, hadToken: true}
+	}
+
+	explicit := p.isFlagSet("branch") || os.Getenv("PINNACLE_BRANCH") != ""
+	if explicit || p.branch == defaultBranch {
+		return &unknownBranchError{branch: p.branch, available: available}
+	}
+
+	p.logger.WarnContext(ctx, fmt.Sprintf("branch %q no longer exists, switching to %s", p.branch, defaultBranch))
+	p.branch = defaultBranch
+	return p.loadToken()
+}
+
+// rememberSelection saves the branch and pinned launcher version given
+// as flags to config.json so later runs use them without passing the
+// flags again. Values from the environment are left out of the file.
+func (p *Pinnacle) rememberSelection() error {
+	cfg, err := p.loadConfig()
+	if err != nil {
+		return err
+	}
+
+	saved := cfg
+	if p.isFlagSet("branch") {
+		cfg.Branch = p.branch
+		if cfg.Branch == defaultBranch {
+			cfg.Branch = ""
+		}
+	}
+	if p.isFlagSet("launcher-version") {
+		cfg.LauncherVersion = p.config.LauncherVersion // the flag wins, "latest" already cleared
+	}
+	if cfg.Branch == saved.Branch && cfg.LauncherVersion == saved.LauncherVersion {
+		return nil
+	}
+	return p.saveConfig(cfg)
+}
+
+// launcherPath returns where the launcher for the current branch is kept.
+// Each branch has its own jar so switching back and forth doesn't
+// download it again.
+func (p *Pinnacle) launcherPath() string {
+	if p.branch == defaultBranch || p.branch == "" {
+		return p.alpinePath("launcher.jar")
+	}
+	return p.alpinePath("launcher-" + p.branch + ".jar")
+}
+
+// Branches prints the branches offered by the metadata server.
+func (p *Pinnacle) Branches(ctx context.Context, w io.Writer, format string) error {
+	branches, err := p.fetchBranches(ctx)
+	if errors.Is(err, errNotFound) {
+		return errors.New("this server doesn't list its branches")
+	}
+	if err != nil {
+		return err
+	}
+
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(branches)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, b := range branches {
+		marker := " "
+		if b.Name == p.branch {
+			marker = "*"
+		}
+		_, _ = fmt.Fprintf(tw, "%s %s\t%s\n", marker, b.Name, b.Description)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLauncherPath(t *testing.T) {
+	tests := []struct {
+		branch string
+		want   string
+	}{
+		{branch: "", want: "launcher.jar"},
+		{branch: defaultBranch, want: "launcher.jar"},
+		{branch: "beta", want: "launcher-beta.jar"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.branch, func(t *testing.T) {
+			p := &Pinnacle{dataDir: "data", branch: tt.branch}
+			if got := p.launcherPath(); got != filepath.Join("data", tt.want) {
+				t.Errorf("launcherPath() = %q, want %q", got, filepath.Join("data", tt.want))
+			}
+		})
+	}
+}
+
+func TestRememberSelection(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		branch    string // effective branch after resolving the configuration
+		version   string // effective launcher version, "latest" already cleared
+		saved     Config
+		want      Config
+		wantWrite bool
+	}{
+		{
+			name:      "branch flag",
+			args:      []string{"-branch", "beta"},
+			branch:    "beta",
+			saved:     Config{JavaMaxHeap: "2G"},
+			want:      Config{Branch: "beta", JavaMaxHeap: "2G"},
+			wantWrite: true,
+		},
+		{
+			name:      "default branch clears",
+			args:      []string{"-branch", defaultBranch},
+			branch:    defaultBranch,
+			saved:     Config{Branch: "beta"},
+			want:      Config{},
+			wantWrite: true,
+		},
+		{name: "same branch", args: []string{"-branch", "beta"}, branch: "beta", saved: Config{Branch: "beta"}, want: Config{Branch: "beta"}},
+		{name: "environment only", branch: "alpha", saved: Config{Branch: "beta"}, want: Config{Branch: "beta"}},
+		{
+			name:      "pinned version",
+			args:      []string{"-launcher-version", "1.2.3"},
+			version:   "1.2.3",
+			saved:     Config{Branch: "beta"},
+			want:      Config{Branch: "beta", LauncherVersion: "1.2.3"},
+			wantWrite: true,
+		},
+		{
+			name:      "latest unpins",
+			args:      []string{"-launcher-version", "latest"},
+			saved:     Config{LauncherVersion: "1.2.3"},
+			want:      Config{},
+			wantWrite: true,
+		},
+		{
+			name:      "branch and version",
+			args:      []string{"-branch", "beta", "-launcher-version", "2.0.0"},
+			branch:    "beta",
+			version:   "2.0.0",
+			want:      Config{Branch: "beta", LauncherVersion: "2.0.0"},
+			wantWrite: true,
+		},
+		{name: "version from environment", version: "1.2.4", saved: Config{}, want: Config{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := testPinnacle(t.TempDir())
+			if _, err := p.parseCommand(append([]string{"run"}, tt.args...)); err != nil {
+				t.Fatal(err)
+			}
+			p.branch = tt.branch
+			p.config.LauncherVersion = tt.version
+			if err := p.saveConfig(tt.saved); err != nil {
+				t.Fatal(err)
+			}
+			path := p.alpinePath("config.json")
+			old := time.Now().Add(-time.Hour).Truncate(time.Second)
+			if err := os.Chtimes(path, old, old); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := p.rememberSelection(); err != nil {
+				t.Fatalf("rememberSelection() error = %v", err)
+			}
+			got, err := p.loadConfig()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Branch != tt.want.Branch || got.LauncherVersion != tt.want.LauncherVersion || got.JavaMaxHeap != tt.want.JavaMaxHeap {
+				t.Errorf("saved config = %+v, want %+v", got, tt.want)
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rewritten := !info.ModTime().Equal(old); rewritten != tt.wantWrite {
+				t.Errorf("config.json rewritten = %t, want %t", rewritten, tt.wantWrite)
+			}
+		})
+	}
+}
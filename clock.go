@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxClockSkew is the difference from server time beyond which
+// certificate failures are blamed on the local clock.
+const maxClockSkew = 24 * time.Hour
+
+// clockSkewError is returned when certificate validation fails
+// and the system clock is far from the server's.
+type clockSkewError struct {
+	skew time.Duration // local time minus server time
+}
+
+func (e *clockSkewError) Error() string {
+	direction := "ahead of"
+	if e.skew < 0 {
+		direction = "behind"
+	}
+	days := int(math.Round(math.Abs(e.skew.Hours()) / 24))
+	return fmt.Sprintf("Your system clock is wrong by %d days (%s the real time), "+
+		"so secure connections cannot be verified.\n\n"+
+		"Please correct your date and time settings and try again.", days, direction)
+}
+
+// recordClockSkew stores the difference between the local clock
+// and the Date header of resp, if present.
+func (p *Pinnacle) recordClockSkew(resp *http.Response) {
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return
+	}
+	p.clockSkew.Store(int64(time.Since(date)))
+	p.clockSkewKnown.Store(true)
+}
+
+// checkClockSkew reports a clockSkewError if the local clock is off by
+// more than maxClockSkew. When no server time has been seen yet, it is
+// fetched from host over plain HTTP, which needs no certificate.
+func (p *Pinnacle) checkClockSkew(ctx context.Context, host string) error {
+	if !p.clockSkewKnown.Load() {
+		p.fetchServerTime(ctx, host)
+	}
+	if !p.clockSkewKnown.Load() {
+		return nil
+	}
+
+	skew := time.Duration(p.clockSkew.Load())
+	if skew.Abs() < maxClockSkew {
+		return nil
+	}
+
+	p.Breadcrumb(ctx, fmt.Sprintf("system clock is off by %s", skew), slog.LevelWarn)
+	return &clockSkewError{skew: skew}
+}
+
+// reportClockSkew sends a warning event tagged with the skew in days if
+// err is a clockSkewError, so wrong clocks are counted without being
+// reported as bugs.
+func (p *Pinnacle) reportClockSkew(ctx context.Context, err error) {
+	var skewErr *clockSkewError
+	if !errors.As(err, &skewErr) {
+		return
+	}
+	p.client.CaptureWarning(ctx, "system clock is wrong", map[string]string{
+		"ClockSkewDays": strconv.Itoa(int(skewErr.skew.Hours() / 24)),
+	})
+}
+
+func (p *Pinnacle) fetchServerTime(ctx context.Context, host string) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, "http://"+host, nil)
+	if err != nil {
+		return
+	}
+	req.Header.Set("User-Agent", fmt.Sprintf("Pinnacle/%s (%s; %s)", version, p.os, p.arch))
+
+	plain := &http.Client{
+		Transport: httpClient.Transport,
+		Timeout:   10 * time.Second,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse // the redirect itself carries a Date
+		},
+	}
+
+	resp, err := plain.Do(req)
+	if err != nil {
+		p.Breadcrumb(ctx, "unable to fetch server time: "+err.Error())
+		return
+	}
+	p.CaptureErr(ctx, resp.Body.Close())
+	p.recordClockSkew(resp)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	sentrygo "github.com/getsentry/sentry-go"
+)
+
+func TestClockSkewError(t *testing.T) {
+	tests := []struct {
+		skew time.Duration
+		want string
+	}{
+		{skew: 72 * time.Hour, want: "wrong by 3 days (ahead of the real time)"},
+		{skew: -400 * 24 * time.Hour, want: "wrong by 400 days (behind the real time)"},
+		{skew: 36*time.Hour + time.Minute, want: "wrong by 2 days (ahead"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.skew.String(), func(t *testing.T) {
+			if got := (&clockSkewError{skew: tt.skew}).Error(); !strings.Contains(got, tt.want) {
+				t.Errorf("Error() = %q, want it to contain %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckClockSkew(t *testing.T) {
+	tests := []struct {
+		name     string
+		date     time.Time // server time in the Date header
+		wantSkew bool
+	}{
+		{name: "in sync", date: time.Now()},
+		{name: "different time zone offset", date: time.Now().Add(-13 * time.Hour)},
+		{name: "clock ahead", date: time.Now().Add(-3 * 24 * time.Hour), wantSkew: true},
+		{name: "clock behind", date: time.Now().Add(400 * 24 * time.Hour), wantSkew: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := testPinnacle(t.TempDir())
+			p.recordClockSkew(&http.Response{Header: http.Header{"Date": {tt.date.UTC().Format(http.TimeFormat)}}})
+			if !p.clockSkewKnown.Load() {
+				t.Fatal("Date header wasn't recorded")
+			}
+
+			// The skew is known, so nothing is fetched from the host.
+			err := p.checkClockSkew(context.Background(), "127.0.0.1:0")
+
+			var skewErr *clockSkewError
+			if errors.As(err, &skewErr) != tt.wantSkew {
+				t.Errorf("checkClockSkew() = %v, want clock skew %t", err, tt.wantSkew)
+			}
+		})
+	}
+}
+
+func TestRecordClockSkewWithoutDate(t *testing.T) {
+	p := testPinnacle(t.TempDir())
+	p.recordClockSkew(&http.Response{Header: http.Header{"Date": {"yesterday"}}})
+	if p.clockSkewKnown.Load() {
+		t.Error("recorded a skew from an invalid Date header")
+	}
+}
+
+func TestReportClockSkew(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		events []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		events = append(events, string(body))
+		mu.Unlock()
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { sentrygo.CurrentHub().BindClient(nil) })
+
+	p := testPinnacle(t.TempDir())
+	if err := p.client.Start("test", "http://public@"+srv.Listener.Addr().String()+"/1"); err != nil {
+		t.Fatal(err)
+	}
+
+	p.reportClockSkew(context.Background(), errors.New("internet failure"))
+	p.reportClockSkew(context.Background(), fmt.Errorf("java: %w", &clockSkewError{skew: -3 * 24 * time.Hour}))
+	sentrygo.Flush(2 * time.Second)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 1 {
+		t.Fatalf("sent %d event(s), want one for the clock skew only", len(events))
+	}
+	for _, want := range []string{`"ClockSkewDays":"-3"`, `"level":"warning"`, "system clock is wrong"} {
+		if !strings.Contains(events[0], want) {
+			t.Errorf("event is missing %s:\n%s", want, events[0])
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+)
+
+// options holds the values of command-line flags.
+type options struct {
+	branch          string
+	launcherVersion string
+	rateLimit       string
+	caFile          string
+	tlsMinVersion   string
+	format          string
+	dataDir         string
+	locked          string
+	output          string
+	json            bool
+	segments        int
+	dryRun          bool
+	all             bool
+	yes             bool
+	keepLogs        bool
+	keepUserData    bool
+}
+
+// command is a pinnacle subcommand such as "run" or "status".
+type command struct {
+	run     func(ctx context.Context, p *Pinnacle) error
+	flags   func(o *options, fs *flag.FlagSet)
+	name    string
+	summary string
+	args    string // positional arguments shown in usage; commands without any reject them
+	// network commands read config.json, configure TLS and proxies and accept network flags.
+	network bool
+	// report commands write logs/updater.log and report errors to Sentry.
+	report bool
+	// noData commands don't use the data directory.
+	noData bool
+	// migrate commands install or launch, so they may move ~/.alpineclient
+	// to the XDG data directory. Every other command leaves it in place.
+	migrate bool
+}
+
+// defaultCommand runs when no command is given, e.g. from a desktop shortcut.
+const defaultCommand = "run"
+
+var commands = []*command{
+	{
+		name:    "run",
+		summary: "Install or update Alpine Client and start the launcher",
+		network: true,
+		report:  true,
+		migrate: true,
+		flags: func(o *options, fs *flag.FlagSet) {
+			fs.BoolVar(&o.dryRun, "dry-run", false, "Print what would be installed without changing anything")
+			fs.StringVar(&o.locked, "locked", "", "Install exactly the artifacts recorded in this lockfile")
+			formatFlag(o, fs)
+		},
+		run: func(_ context.Context, p *Pinnacle) error {
+			if p.opts.locked != "" {
+				if err := p.loadLock(p.opts.locked); err != nil {
+					return err
+				}
+			}
+			if p.opts.dryRun {
+				return p.Plan(os.Stdout, p.opts.format)
+			}
+			return p.Run()
+		},
+	},
+	{
+		name:    "status",
+		summary: "Show what is installed and whether it is up to date",
+		network: true,
+		flags: func(o *options, fs *flag.FlagSet) {
+			formatFlag(o, fs)
+			fs.BoolVar(&o.json, "json", false, "Shorthand for -format json")
+		},
+		run: func(ctx context.Context, p *Pinnacle) error {
+			return p.Status(ctx, os.Stdout, p.opts.format)
+		},
+	},
+	{
+		name:    "repair",
+		summary: "Verify every file, redownload anything broken and clear temporary files",
+		network: true,
+		report:  true,
+		migrate: true,
+		run: func(ctx context.Context, p *Pinnacle) error {
+			report, err := p.Repair(ctx)
+			fmt.Print(report)
+			return err
+		},
+	},
+	{
+		name:    "clean",
+		summary: "Remove temporary files left by interrupted installs",
+		network: true,
+		flags: func(o *options, fs *flag.FlagSet) {
+			fs.BoolVar(&o.all, "all", false, "Also remove installed components so the next run downloads them again")
+		},
+		run: func(ctx context.Context, p *Pinnacle) error {
+			return p.Clean(ctx, os.Stdout)
+		},
+	},
+	{
+		name:    "doctor",
+		summary: "Diagnose common installation and network problems",
+		network: true,
+		run: func(ctx context.Context, p *Pinnacle) error {
+			return p.Doctor(ctx, os.Stdout)
+		},
+	},
+	{
+		name:    "branches",
+		summary: "List the launcher branches you can switch to",
+		network: true,
+		flags:   formatFlag,
+		run: func(ctx context.Context, p *Pinnacle) error {
+			return p.Branches(ctx, os.Stdout, p.opts.format)
+		},
+	},
+	{
+		name:    "login",
+		summary: "Save an access token for a private branch, read from standard input",
+		network: true,
+		run: func(ctx context.Context, p *Pinnacle) error {
+			return p.Login(ctx, os.Stdin, os.Stdout)
+		},
+	},
+	{
+		name:    "logout",
+		summary: "Remove the saved access token for a branch",
+		network: true,
+		run: func(_ context.Context, p *Pinnacle) error {
+			return p.Logout(os.Stdout)
+		},
+	},
+	{
+		name:    "lock",
+		summary: "Write a lockfile recording the artifacts a run would install",
+		network: true,
+		flags: func(o *options, fs *flag.FlagSet) {
+			fs.StringVar(&o.output, "o", "pinnacle.lock", "Lockfile to write, or - for standard output")
+		},
+		run: func(ctx context.Context, p *Pinnacle) error {
+			return p.Lock(ctx, os.Stdout, p.opts.output)
+		},
+	},
+	{
+		name:    "config",
+		summary: "Show or change settings",
+		args:    "[list | get <key> | set <key> <value> | unset <key>]",
+		run: func(_ context.Context, p *Pinnacle) error {
+			return p.Configure(os.Stdout, p.flags.Args())
+		},
+	},
+	{
+		name:    "version",
+		summary: "Print the Pinnacle version",
+		noData:  true,
+		run: func(_ context.Context, p *Pinnacle) error {
+			fmt.Printf("pinnacle %s (%s/%s, %s)\n", displayVersion(), p.os, p.arch, runtime.Version())
+			return nil
+		},
+	},
+	{
+		name:    "uninstall",
+		summary: "Remove Alpine Client data installed by Pinnacle",
+		flags: func(o *options, fs *flag.FlagSet) {
+			fs.BoolVar(&o.yes, "yes", false, "Don't ask for confirmation")
+			fs.BoolVar(&o.keepLogs, "keep-logs", false, "Keep the logs directory")
+			fs.BoolVar(&o.keepUserData, "keep-user-data", false, "Only remove files Pinnacle installed, keeping data written by the launcher")
+		},
+		run: func(_ context.Context, p *Pinnacle) error {
+			return p.Uninstall(os.Stdin, os.Stdout)
+		},
+	},
+}
+
+func formatFlag(o *options, fs *flag.FlagSet) {
+	fs.StringVar(&o.format, "format", "text", "Output format (text or json)")
+}
+
+// networkFlags registers the flags shared by every network command.
+func networkFlags(o *options, fs *flag.FlagSet) {
+	fs.StringVar(&o.branch, "branch", "", "Launcher branch, remembered by run (default production)")
+	fs.StringVar(&o.launcherVersion, "launcher-version", "", "Stay on this launcher version, remembered by run until set to \"latest\"")
+	fs.IntVar(&o.segments, "segments", 0, "Parallel connections for large downloads (default 1, which disables)")
+	fs.StringVar(&o.rateLimit, "rate-limit", "", "Maximum download speed in bytes/second, e.g. 500K or 2M")
+	fs.StringVar(&o.caFile, "ca-file", "", "Additional PEM certificate authorities to trust")
+	fs.StringVar(&o.tlsMinVersion, "tls-min-version", "", "Minimum TLS version (1.2 or 1.3)")
+}
+
+// parseCommand selects the command named by the first argument, falling
+// back to the default command when the arguments start with a flag or are
+// empty, and parses the remaining arguments as that command's flags.
+func (p *Pinnacle) parseCommand(args []string) (*command, error) {
+	name := defaultCommand
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	if name == "help" {
+		printUsage(os.Stdout)
+		return nil, flag.ErrHelp
+	}
+
+	var cmd *command
+	for _, c := range commands {
+		if c.name == name {
+			cmd = c
+		}
+	}
+	if cmd == nil {
+		printUsage(os.Stderr)
+		return nil, fmt.Errorf("unknown command %q", name)
+	}
+
+	fs := flag.NewFlagSet("pinnacle "+cmd.name, flag.ContinueOnError)
+	if !cmd.noData {
+		fs.StringVar(&p.opts.dataDir, "data-dir", "", "Data directory, overriding PINNACLE_DATA_DIR and the default")
+	}
+	if cmd.network {
+		networkFlags(&p.opts, fs)
+	}
+	if cmd.flags != nil {
+		cmd.flags(&p.opts, fs)
+	}
+	fs.Usage = func() {
+		out := fs.Output()
+		_, _ = fmt.Fprintf(out, "Usage: pinnacle %s [flags] %s\n\n%s.\n\nFlags:\n", cmd.name, cmd.args, cmd.summary)
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 && cmd.args == "" {
+		return nil, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+	if p.opts.json {
+		p.opts.format = "json"
+	}
+	if p.opts.format != "" && p.opts.format != "text" && p.opts.format != "json" {
+		return nil, fmt.Errorf("unknown format %q", p.opts.format)
+	}
+
+	p.flags = fs
+	return cmd, nil
+}
+
+// isFlagSet reports whether the named flag was passed on the command line.
+func (p *Pinnacle) isFlagSet(name string) bool {
+	set := false
+	p.flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+func printUsage(w io.Writer) {
+	_, _ = fmt.Fprintln(w, "Usage: pinnacle [command] [flags]\n\nCommands:")
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, c := range commands {
+		_, _ = fmt.Fprintf(tw, "  %s\t%s\n", c.name, c.summary)
+	}
+	_ = tw.Flush()
+	_, _ = fmt.Fprintf(w, "\nWithout a command, %q is used. Run \"pinnacle <command> -h\" for its flags.\n", defaultCommand)
+}
+
+// confirm asks a yes/no question on the terminal, defaulting to no.
+func confirm(in io.Reader, out io.Writer, question string) bool {
+	_, _ = fmt.Fprintf(out, "%s [y/N] ", question)
+
+	var answer string
+	if _, err := fmt.Fscanln(in, &answer); err != nil && !errors.Is(err, io.EOF) {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// displayVersion returns the build version, or "dev" for local builds.
+func displayVersion() string {
+	if version == "" {
+		return "dev"
+	}
+	return version
+}
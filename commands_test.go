@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"testing"
+)
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantCmd  string
+		wantErr  bool
+		wantOpts func(o options) bool
+		wantSet  string // a flag isFlagSet must report
+	}{
+		{name: "no arguments", args: nil, wantCmd: defaultCommand},
+		{name: "flags only", args: []string{"-dry-run"}, wantCmd: "run", wantOpts: func(o options) bool { return o.dryRun }},
+		{name: "named command", args: []string{"status"}, wantCmd: "status"},
+		{
+			name:     "json shorthand",
+			args:     []string{"status", "-json"},
+			wantCmd:  "status",
+			wantOpts: func(o options) bool { return o.format == "json" },
+		},
+		{
+			name:     "network flag",
+			args:     []string{"doctor", "-branch", "beta"},
+			wantCmd:  "doctor",
+			wantOpts: func(o options) bool { return o.branch == "beta" },
+			wantSet:  "branch",
+		},
+		{
+			name:     "data directory",
+			args:     []string{"uninstall", "-data-dir", "/tmp/alpine", "-yes"},
+			wantCmd:  "uninstall",
+			wantOpts: func(o options) bool { return o.dataDir == "/tmp/alpine" && o.yes },
+			wantSet:  "data-dir",
+		},
+		{name: "positional arguments", args: []string{"config", "get", "branch"}, wantCmd: "config"},
+		{name: "unknown command", args: []string{"bogus"}, wantErr: true},
+		{name: "unexpected argument", args: []string{"status", "extra"}, wantErr: true},
+		{name: "unknown flag", args: []string{"status", "-bogus"}, wantErr: true},
+		{name: "no data directory flag", args: []string{"version", "-data-dir", "/tmp"}, wantErr: true},
+		{name: "no network flags", args: []string{"uninstall", "-branch", "beta"}, wantErr: true},
+		{name: "unknown format", args: []string{"status", "-format", "xml"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Pinnacle{}
+			cmd, err := p.parseCommand(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseCommand(%q) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if cmd.name != tt.wantCmd {
+				t.Errorf("parseCommand(%q) = %q, want %q", tt.args, cmd.name, tt.wantCmd)
+			}
+			if tt.wantOpts != nil && !tt.wantOpts(p.opts) {
+				t.Errorf("parseCommand(%q) options = %+v", tt.args, p.opts)
+			}
+			if tt.wantSet != "" && !p.isFlagSet(tt.wantSet) {
+				t.Errorf("isFlagSet(%q) = false after parseCommand(%q)", tt.wantSet, tt.args)
+			}
+		})
+	}
+}
+
+func TestParseCommandHelp(t *testing.T) {
+	for _, args := range [][]string{{"help"}, {"status", "-h"}} {
+		if _, err := (&Pinnacle{}).parseCommand(args); !errors.Is(err, flag.ErrHelp) {
+			t.Errorf("parseCommand(%q) error = %v, want flag.ErrHelp", args, err)
+		}
+	}
+}
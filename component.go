@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/alpine-client/pinnacle/ui"
+)
+
+// errNeedsInstall is wrapped by the error a Component's Check
+// returns when it is missing or out of date.
+var errNeedsInstall = errors.New("needs install")
+
+// Component is a part of the Alpine Client installation that
+// Pinnacle keeps up to date, such as the Java runtime or the launcher.
+type Component interface {
+	// Name identifies the component in logs, Sentry and Requires lists.
+	Name() string
+	// Requires lists the names of components that must be ready first.
+	Requires() []string
+	// Check fetches the component's metadata and returns it along with
+	// an error wrapping errNeedsInstall if Install has to run.
+	Check(ctx context.Context) (*MetadataResponse, error)
+	// Install downloads and installs the component described by meta.
+	Install(ctx context.Context, meta *MetadataResponse) error
+	// Verify confirms a finished install matches meta.
+	Verify(ctx context.Context, meta *MetadataResponse) error
+	// Paths lists the files and directories owned by the component.
+	Paths() []string
+}
+
+// register adds components to the pipeline run by Run.
+func (p *Pinnacle) register(components ...Component) {
+	p.components = append(p.components, components...)
+}
+
+// maxConcurrentComponents bounds how many components are
+// checked and installed at the same time.
+const maxConcurrentComponents = 3
+
+// ensureAll checks and installs every registered component. Components
+// run concurrently once everything they require is ready, and the first
+// failure cancels the rest. On failure it returns the failed component's
+// context so the error is reported against the right Sentry hub, detached
+// from the cancellation so the error dialog can still open a browser.
+func (p *Pinnacle) ensureAll(ctx context.Context) (context.Context, error) {
+	components, err := orderComponents(p.components)
+	if err != nil {
+		return ctx, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ready := make(map[string]chan struct{}, len(components))
+	for _, c := range components {
+		ready[c.Name()] = make(chan struct{})
+	}
+
+	var (
+		wg        sync.WaitGroup
+		once      sync.Once
+		failedCtx context.Context
+		failedErr error
+	)
+	slots := make(chan struct{}, maxConcurrentComponents)
+
+	for _, c := range components {
+		wg.Go(func() {
+			defer close(ready[c.Name()])
+
+			for _, name := range c.Requires() {
+				select {
+				case <-ready[name]:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if ctx.Err() != nil {
+				return // a dependency failed
+			}
+
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			cctx := p.client.NewContext(ctx, c.Name())
+			if cerr := p.ensure(cctx, c); cerr != nil {
+				once.Do(func() {
+					failedCtx, failedErr = cctx, cerr
+					cancel()
+				})
+			}
+		})
+	}
+	wg.Wait()
+
+	if failedCtx == nil {
+		return nil, nil
+	}
+	return context.WithoutCancel(failedCtx), failedErr
+}
+
+// ensure checks c and installs it if needed.
+func (p *Pinnacle) ensure(ctx context.Context, c Component) error {
+	meta, err := c.Check(ctx)
+	if !errors.Is(err, errNeedsInstall) {
+		return err
+	}
+
+	p.Breadcrumb(ctx, fmt.Sprintf("installing %s: %v", c.Name(), err))
+	ui.Render(p.logger)
+
+	err = c.Install(ctx, meta)
+	if err != nil {
+		return err
+	}
+	return c.Verify(ctx, meta)
+}
+
+// removeComponents deletes every path owned by a registered component.
+func (p *Pinnacle) removeComponents(ctx context.Context) {
+	for _, c := range p.components {
+		for _, path := range c.Paths() {
+			p.CaptureErr(ctx, os.RemoveAll(path))
+		}
+	}
+}
+
+// orderComponents sorts components so that each one comes after
+// everything it requires, keeping registration order otherwise.
+func orderComponents(components []Component) ([]Component, error) {
+	byName := make(map[string]Component, len(components))
+	for _, c := range components {
+		if _, dup := byName[c.Name()]; dup {
+			return nil, fmt.Errorf("component %q registered twice", c.Name())
+		}
+		byName[c.Name()] = c
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(components))
+	ordered := make([]Component, 0, len(components))
+
+	var visit func(c Component) error
+	visit = func(c Component) error {
+		switch state[c.Name()] {
+		case visiting:
+			return fmt.Errorf("component %q has a circular dependency", c.Name())
+		case done:
+			return nil
+		}
+		state[c.Name()] = visiting
+		for _, name := range c.Requires() {
+			dep, found := byName[name]
+			if !found {
+				return fmt.Errorf("component %q requires unknown component %q", c.Name(), name)
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		state[c.Name()] = done
+		ordered = append(ordered, c)
+		return nil
+	}
+
+	for _, c := range components {
+		if err := visit(c); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
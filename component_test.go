@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/alpine-client/pinnacle/sentry"
+)
+
+// testPinnacle returns a Pinnacle that keeps all data in dir
+// and discards logs and Sentry reports.
+func testPinnacle(dir string) *Pinnacle {
+	logger := slog.New(slog.DiscardHandler)
+	return &Pinnacle{
+		logger:   logger,
+		client:   sentry.New(logger),
+		dataDir:  dir,
+		cacheDir: dir,
+		stateDir: dir,
+	}
+}
+
+// fakeComponent only has a name and dependencies, and
+// optionally fails its check.
+type fakeComponent struct {
+	name     string
+	requires []string
+	err      error
+	checked  bool
+}
+
+func (c *fakeComponent) Name() string       { return c.name }
+func (c *fakeComponent) Requires() []string { return c.requires }
+func (*fakeComponent) Paths() []string      { return nil }
+
+func (c *fakeComponent) Check(context.Context) (*MetadataResponse, error) {
+	c.checked = true
+	return &MetadataResponse{}, c.err
+}
+
+func (*fakeComponent) Install(context.Context, *MetadataResponse) error { return nil }
+
+func (*fakeComponent) Verify(context.Context, *MetadataResponse) error { return nil }
+
+// components builds fake components from "name:dep,dep" specs.
+func components(specs ...string) []Component {
+	out := make([]Component, 0, len(specs))
+	for _, spec := range specs {
+		name, deps, _ := strings.Cut(spec, ":")
+		c := &fakeComponent{name: name}
+		if deps != "" {
+			c.requires = strings.Split(deps, ",")
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+func TestOrderComponents(t *testing.T) {
+	tests := []struct {
+		name    string
+		specs   []string
+		want    []string
+		wantErr string
+	}{
+		{name: "none", specs: nil, want: []string{}},
+		{name: "registration order", specs: []string{"java", "launcher"}, want: []string{"java", "launcher"}},
+		{name: "dependency first", specs: []string{"launcher:java", "java"}, want: []string{"java", "launcher"}},
+		{
+			name:  "chain",
+			specs: []string{"mods:launcher", "launcher:java", "java"},
+			want:  []string{"java", "launcher", "mods"},
+		},
+		{
+			name:  "shared dependency",
+			specs: []string{"a:c", "b:c", "c"},
+			want:  []string{"c", "a", "b"},
+		},
+		{name: "duplicate", specs: []string{"java", "java"}, wantErr: `component "java" registered twice`},
+		{name: "unknown dependency", specs: []string{"launcher:jre"}, wantErr: `requires unknown component "jre"`},
+		{name: "self dependency", specs: []string{"java:java"}, wantErr: "circular dependency"},
+		{name: "cycle", specs: []string{"a:b", "b:c", "c:a"}, wantErr: "circular dependency"},
+		{name: "cycle behind dependency", specs: []string{"java", "a:java,b", "b:a"}, wantErr: "circular dependency"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ordered, err := orderComponents(components(tt.specs...))
+			checkErrContains(t, err, tt.wantErr)
+			if err != nil {
+				return
+			}
+
+			got := make([]string, 0, len(ordered))
+			for _, c := range ordered {
+				got = append(got, c.Name())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("orderComponents() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnsureAllFailure(t *testing.T) {
+	errBroken := errors.New("broken")
+	java := &fakeComponent{name: "java", err: errBroken}
+	launcher := &fakeComponent{name: "launcher", requires: []string{"java"}}
+
+	p := testPinnacle(t.TempDir())
+	p.register(launcher, java)
+
+	ctx, err := p.ensureAll(context.Background())
+	if !errors.Is(err, errBroken) {
+		t.Fatalf("ensureAll() error = %v, want %v", err, errBroken)
+	}
+	if launcher.checked {
+		t.Error("launcher was checked although java failed")
+	}
+	if ctx.Err() != nil {
+		t.Errorf("returned context is done (%v), error dialogs couldn't open a browser", ctx.Err())
+	}
+}
+
+func TestEnsureAllSuccess(t *testing.T) {
+	p := testPinnacle(t.TempDir())
+	cs := components("mods:launcher", "launcher:java", "java")
+	p.register(cs...)
+
+	if _, err := p.ensureAll(context.Background()); err != nil {
+		t.Fatalf("ensureAll() error = %v", err)
+	}
+	for _, c := range cs {
+		if !c.(*fakeComponent).checked {
+			t.Errorf("%s wasn't checked", c.Name())
+		}
+	}
+}
+
+// recordingComponent appends its name to a shared log when checked.
+type recordingComponent struct {
+	fakeComponent
+	mu  *sync.Mutex
+	log *[]string
+}
+
+func (c *recordingComponent) Check(ctx context.Context) (*MetadataResponse, error) {
+	c.mu.Lock()
+	*c.log = append(*c.log, c.name)
+	c.mu.Unlock()
+	return c.fakeComponent.Check(ctx)
+}
+
+func TestEnsureAllWaitsForDependencies(t *testing.T) {
+	var (
+		mu  sync.Mutex
+		log []string
+	)
+	p := testPinnacle(t.TempDir())
+	for _, c := range components("mods:launcher", "shaders:launcher", "launcher:java", "java") {
+		p.register(&recordingComponent{fakeComponent: *c.(*fakeComponent), mu: &mu, log: &log})
+	}
+
+	for range 20 {
+		log = nil
+		if _, err := p.ensureAll(context.Background()); err != nil {
+			t.Fatalf("ensureAll() error = %v", err)
+		}
+		if len(log) != 4 || log[0] != "java" || log[1] != "launcher" {
+			t.Fatalf("checked in order %q, want java, then launcher, then mods and shaders", log)
+		}
+	}
+}
@@ -0,0 +1,557 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Config holds user settings read from config.json
+// in the data directory. Missing keys keep their defaults.
+type Config struct {
+	Branch          string       `json:"branch,omitempty"`
+	LauncherVersion string       `json:"launcher_version,omitempty"`
+	JavaMinHeap     string       `json:"java_min_heap,omitempty"`
+	JavaMaxHeap     string       `json:"java_max_heap,omitempty"`
+	ConnectTimeout  string       `json:"connect_timeout,omitempty"`
+	ResponseTimeout string       `json:"response_timeout,omitempty"`
+	Retries         *int         `json:"retries,omitempty"`
+	Segments        int          `json:"segments,omitempty"`
+	RateLimit       string       `json:"rate_limit,omitempty"`
+	TLSMinVersion   string       `json:"tls_min_version,omitempty"`
+	Proxy           *ProxyConfig `json:"proxy,omitempty"`
+	CAFiles         []string     `json:"ca_files,omitempty"`
+}
+
+// defaultConfig returns the built-in settings.
+func defaultConfig() Config {
+	retries := 3
+	return Config{
+		Branch:          defaultBranch,
+		JavaMinHeap:     "256M",
+		JavaMaxHeap:     "256M",
+		ConnectTimeout:  "30s",
+		ResponseTimeout: "15s",
+		Retries:         &retries,
+		Segments:        1,
+	}
+}
+
+// setting is a single config key. Values are handled as strings so the
+// same parsing and validation applies to config.json, environment
+// variables, flags and "pinnacle config set".
+type setting struct {
+	get   func(c *Config) string
+	set   func(c *Config, value string) error // an empty value clears the key
+	key   string
+	flag  string // command-line flag overriding the key, if any
+	usage string
+}
+
+// env returns the environment variable overriding the setting.
+func (s *setting) env() string {
+	return "PINNACLE_" + strings.ToUpper(strings.ReplaceAll(s.key, ".", "_"))
+}
+
+var (
+	branchPattern   = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+	javaSizePattern = regexp.MustCompile(`^[0-9]+[kKmMgG]?$`)
+	versionPattern  = regexp.MustCompile(`^[0-9A-Za-z][0-9A-Za-z._+-]*$`)
+)
+
+var settings = []*setting{
+	{
+		key:   "branch",
+		flag:  "branch",
+		usage: "Launcher branch, see \"pinnacle branches\"",
+		get:   func(c *Config) string { return c.Branch },
+		set: func(c *Config, v string) error {
+			if v != "" && !branchPattern.MatchString(v) {
+				return fmt.Errorf("invalid branch name %q", v)
+			}
+			c.Branch = v
+			return nil
+		},
+	},
+	{
+		key:   "launcher_version",
+		flag:  "launcher-version",
+		usage: "Launcher version to stay on instead of updating (\"latest\" clears)",
+		get:   func(c *Config) string { return c.LauncherVersion },
+		set: func(c *Config, v string) error {
+			if v == "latest" {
+				v = ""
+			}
+			if v != "" && !versionPattern.MatchString(v) {
+				return fmt.Errorf("invalid version %q", v)
+			}
+			c.LauncherVersion = v
+			return nil
+		},
+	},
+	{
+		key:   "java_min_heap",
+		usage: "Initial Java heap size for the launcher, e.g. 256M",
+		get:   func(c *Config) string { return c.JavaMinHeap },
+		set:   func(c *Config, v string) error { return setJavaSize(&c.JavaMinHeap, v) },
+	},
+	{
+		key:   "java_max_heap",
+		usage: "Maximum Java heap size for the launcher, e.g. 1G",
+		get:   func(c *Config) string { return c.JavaMaxHeap },
+		set:   func(c *Config, v string) error { return setJavaSize(&c.JavaMaxHeap, v) },
+	},
+	{
+		key:   "connect_timeout",
+		usage: "How long to wait when connecting to a server, e.g. 30s",
+		get:   func(c *Config) string { return c.ConnectTimeout },
+		set:   func(c *Config, v string) error { return setDuration(&c.ConnectTimeout, v) },
+	},
+	{
+		key:   "response_timeout",
+		usage: "How long to wait for a server to respond, e.g. 15s",
+		get:   func(c *Config) string { return c.ResponseTimeout },
+		set:   func(c *Config, v string) error { return setDuration(&c.ResponseTimeout, v) },
+	},
+	{
+		key:   "retries",
+		usage: "Times a failed request is retried (0-10)",
+		get: func(c *Config) string {
+			if c.Retries == nil {
+				return ""
+			}
+			return strconv.Itoa(*c.Retries)
+		},
+		set: func(c *Config, v string) error {
+			if v == "" {
+				c.Retries = nil
+				return nil
+			}
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 || n > 10 {
+				return fmt.Errorf("must be a number from 0 to 10, got %q", v)
+			}
+			c.Retries = &n
+			return nil
+		},
+	},
+	{
+		key:   "segments",
+		flag:  "segments",
+		usage: "Parallel connections for large downloads (1 disables)",
+		get: func(c *Config) string {
+			if c.Segments == 0 {
+				return ""
+			}
+			return strconv.Itoa(c.Segments)
+		},
+		set: func(c *Config, v string) error {
+			if v == "" {
+				c.Segments = 0
+				return nil
+			}
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > 16 {
+				return fmt.Errorf("must be a number from 1 to 16, got %q", v)
+			}
+			c.Segments = n
+			return nil
+		},
+	},
+	{
+		key:   "rate_limit",
+		flag:  "rate-limit",
+		usage: "Maximum download speed in bytes/second, e.g. 500K or 2M",
+		get:   func(c *Config) string { return c.RateLimit },
+		set: func(c *Config, v string) error {
+			if _, err := parseByteSize(v); err != nil {
+				return err
+			}
+			c.RateLimit = v
+			return nil
+		},
+	},
+	{
+		key:   "tls_min_version",
+		flag:  "tls-min-version",
+		usage: "Minimum TLS version (1.2 or 1.3)",
+		get:   func(c *Config) string { return c.TLSMinVersion },
+		set: func(c *Config, v string) error {
+			if _, found := tlsVersions[strings.TrimPrefix(v, "TLS")]; v != "" && !found {
+				return fmt.Errorf("unsupported TLS version %q", v)
+			}
+			c.TLSMinVersion = v
+			return nil
+		},
+	},
+	{
+		key:   "ca_files",
+		flag:  "ca-file",
+		usage: "Comma-separated PEM certificate authorities to trust",
+		get:   func(c *Config) string { return strings.Join(c.CAFiles, ",") },
+		set: func(c *Config, v string) error {
+			c.CAFiles = splitList(v)
+			return nil
+		},
+	},
+	{
+		key:   "proxy.url",
+		usage: "Proxy server, overriding system settings",
+		get: func(c *Config) string {
+			if c.Proxy == nil {
+				return ""
+			}
+			return c.Proxy.URL
+		},
+		set: func(c *Config, v string) error {
+			if v != "" {
+				if _, err := parseProxyURL(v); err != nil {
+					return err
+				}
+			}
+			c.proxy().URL = v
+			return nil
+		},
+	},
+	{
+		key:   "proxy.username",
+		usage: "Proxy username",
+		get: func(c *Config) string {
+			if c.Proxy == nil {
+				return ""
+			}
+			return c.Proxy.Username
+		},
+		set: func(c *Config, v string) error {
+			c.proxy().Username = v
+			return nil
+		},
+	},
+	{
+		key:   "proxy.password",
+		usage: "Proxy password",
+		get: func(c *Config) string {
+			if c.Proxy == nil {
+				return ""
+			}
+			return c.Proxy.Password
+		},
+		set: func(c *Config, v string) error {
+			c.proxy().Password = v
+			return nil
+		},
+	},
+	{
+		key:   "proxy.ignore_hosts",
+		usage: "Comma-separated hosts to connect to directly",
+		get: func(c *Config) string {
+			if c.Proxy == nil {
+				return ""
+			}
+			return strings.Join(c.Proxy.Ignore, ",")
+		},
+		set: func(c *Config, v string) error {
+			c.proxy().Ignore = splitList(v)
+			return nil
+		},
+	},
+}
+
+// proxy returns c.Proxy, creating it if needed.
+func (c *Config) proxy() *ProxyConfig {
+	if c.Proxy == nil {
+		c.Proxy = &ProxyConfig{}
+	}
+	return c.Proxy
+}
+
+func setJavaSize(field *string, v string) error {
+	if v != "" && !javaSizePattern.MatchString(v) {
+		return fmt.Errorf("invalid size %q, expected a number with an optional K, M or G suffix", v)
+	}
+	*field = v
+	return nil
+}
+
+// parseJavaSize returns the bytes in a size accepted by setJavaSize. Like
+// -Xms and -Xmx, K, M and G are powers of 1024, so 1024M equals 1G.
+func parseJavaSize(v string) int64 {
+	if v == "" {
+		return 0
+	}
+	var shift uint
+	switch v[len(v)-1] {
+	case 'k', 'K':
+		shift = 10
+	case 'm', 'M':
+		shift = 20
+	case 'g', 'G':
+		shift = 30
+	}
+	if shift > 0 {
+		v = v[:len(v)-1]
+	}
+	n, _ := strconv.ParseInt(v, 10, 64)
+	return n << shift
+}
+
+func setDuration(field *string, v string) error {
+	if v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid duration %q, expected e.g. 30s or 1m", v)
+		}
+	}
+	*field = v
+	return nil
+}
+
+func splitList(v string) []string {
+	var items []string
+	for item := range strings.SplitSeq(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func findSetting(key string) (*setting, error) {
+	for _, s := range settings {
+		if s.key == key {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown config key %q, see \"pinnacle config list\"", key)
+}
+
+func (p *Pinnacle) loadConfig() (Config, error) {
+	var cfg Config
+
+	data, err := os.ReadFile(p.alpinePath("config.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+
+	if err = json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("invalid config.json: %w", err)
+	}
+	return cfg, nil
+}
+
+func (p *Pinnacle) saveConfig(cfg Config) error {
+	if cfg.Proxy != nil && cfg.Proxy.URL == "" && cfg.Proxy.Username == "" && cfg.Proxy.Password == "" && len(cfg.Proxy.Ignore) == 0 {
+		cfg.Proxy = nil
+	}
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(p.alpinePath(), os.ModePerm); err != nil {
+		return err
+	}
+	path := p.alpinePath("config.json")
+	if err = os.WriteFile(path+".tmp", data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// resolvedSetting is the effective value of a setting and where it came from.
+type resolvedSetting struct {
+	err    error
+	key    string
+	value  string
+	source string // "default", "config.json", an environment variable or a flag
+}
+
+// resolveSettings layers command-line flags over PINNACLE_* environment
+// variables over config.json over the built-in defaults.
+func (p *Pinnacle) resolveSettings(file Config) (Config, []resolvedSetting) {
+	cfg := defaultConfig()
+	resolved := make([]resolvedSetting, 0, len(settings))
+
+	for _, s := range settings {
+		r := resolvedSetting{key: s.key, value: s.get(&cfg), source: "default"}
+		if v := s.get(&file); v != "" {
+			r.value, r.source = v, "config.json"
+		}
+		if v := os.Getenv(s.env()); v != "" {
+			r.value, r.source = v, s.env()
+		}
+		if s.flag != "" && p.flags != nil && p.isFlagSet(s.flag) {
+			r.value, r.source = p.flags.Lookup(s.flag).Value.String(), "-"+s.flag
+		}
+
+		if r.source != "default" {
+			if err := s.set(&cfg, r.value); err != nil {
+				r.err = fmt.Errorf("%s: %s: %w", r.source, s.key, err)
+			}
+		}
+		resolved = append(resolved, r)
+	}
+	return cfg, resolved
+}
+
+// validate checks settings that depend on each other.
+func (c *Config) validate() error {
+	minHeap := parseJavaSize(c.JavaMinHeap)
+	maxHeap := parseJavaSize(c.JavaMaxHeap)
+	if minHeap > maxHeap {
+		return fmt.Errorf("java_min_heap (%s) is larger than java_max_heap (%s)", c.JavaMinHeap, c.JavaMaxHeap)
+	}
+	return nil
+}
+
+// configError is returned by setup when the settings can't be loaded
+// or are invalid.
+type configError struct {
+	err error
+}
+
+func (e *configError) Error() string {
+	return e.err.Error()
+}
+
+func (e *configError) Unwrap() error {
+	return e.err
+}
+
+// resolveConfig returns the effective configuration, or an error
+// describing every invalid setting and where it was set.
+func (p *Pinnacle) resolveConfig() (Config, error) {
+	file, err := p.loadConfig()
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg, resolved := p.resolveSettings(file)
+	var errs []error
+	for _, r := range resolved {
+		if r.err != nil {
+			errs = append(errs, r.err)
+		}
+	}
+
+	if err = cfg.validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if len(errs) > 0 {
+		lines := make([]string, len(errs))
+		for i, err := range errs {
+			lines[i] = err.Error()
+		}
+		return Config{}, fmt.Errorf("invalid configuration:\n  %s", strings.Join(lines, "\n  "))
+	}
+	return cfg, nil
+}
+
+// Configure implements "pinnacle config list|get|set|unset".
+func (p *Pinnacle) Configure(w io.Writer, args []string) error {
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+
+	switch action := args[0]; {
+	case action == "list" && len(args) == 1:
+		return p.listConfig(w)
+	case action == "get" && len(args) == 2:
+		s, err := findSetting(args[1])
+		if err != nil {
+			return err
+		}
+		cfg, err := p.resolveConfig()
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintln(w, s.get(&cfg))
+		return nil
+	case action == "set" && len(args) == 3, action == "unset" && len(args) == 2:
+		s, err := findSetting(args[1])
+		if err != nil {
+			return err
+		}
+		file, err := p.loadConfig()
+		if err != nil {
+			return err
+		}
+		value := ""
+		if action == "set" {
+			value = args[2]
+		}
+		if err = s.set(&file, value); err != nil {
+			return fmt.Errorf("%s: %w", s.key, err)
+		}
+		if cfg, _ := p.resolveSettings(file); cfg.validate() != nil {
+			return cfg.validate()
+		}
+		if err = p.saveConfig(file); err != nil {
+			return err
+		}
+		if v := os.Getenv(s.env()); v != "" {
+			_, _ = fmt.Fprintf(w, "Note: %s=%s overrides this setting.\n", s.env(), v)
+		}
+		return nil
+	default:
+		printConfigUsage(w)
+		return errors.New("invalid arguments")
+	}
+}
+
+func printConfigUsage(w io.Writer) {
+	_, _ = fmt.Fprintln(w, "Usage: pinnacle config [list | get <key> | set <key> <value> | unset <key>]\n\nKeys:")
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, s := range settings {
+		_, _ = fmt.Fprintf(tw, "  %s\t%s\n", s.key, s.usage)
+	}
+	_ = tw.Flush()
+}
+
+func (p *Pinnacle) listConfig(w io.Writer) error {
+	file, err := p.loadConfig()
+	if err != nil {
+		return err
+	}
+	_, resolved := p.resolveSettings(file)
+	slices.SortStableFunc(resolved, func(a, b resolvedSetting) int {
+		return strings.Compare(a.key, b.key)
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE")
+	for _, r := range resolved {
+		value := r.value
+		if strings.HasSuffix(r.key, "password") && value != "" {
+			value = "********"
+		}
+		if r.err != nil {
+			value += " (invalid)"
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\n", r.key, value, r.source)
+	}
+	if err = tw.Flush(); err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(w, "\nSettings are read from %s and can be overridden with\n"+
+		"PINNACLE_<KEY> environment variables, e.g. PINNACLE_JAVA_MAX_HEAP=1G.\n", p.alpinePath("config.json"))
+	for _, r := range resolved {
+		if r.err != nil {
+			_, _ = fmt.Fprintln(w, r.err)
+		}
+	}
+	return nil
+}
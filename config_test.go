@@ -0,0 +1,140 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolveSettings(t *testing.T) {
+	tests := []struct {
+		name       string
+		file       Config
+		env        map[string]string
+		args       []string
+		key        string
+		wantValue  string
+		wantSource string
+		wantErr    string
+	}{
+		{name: "default", key: "branch", wantValue: "production", wantSource: "default"},
+		{name: "config file", file: Config{Branch: "beta"}, key: "branch", wantValue: "beta", wantSource: "config.json"},
+		{
+			name:       "environment over config file",
+			file:       Config{Branch: "beta"},
+			env:        map[string]string{"PINNACLE_BRANCH": "nightly"},
+			key:        "branch",
+			wantValue:  "nightly",
+			wantSource: "PINNACLE_BRANCH",
+		},
+		{
+			name:       "flag over environment",
+			file:       Config{Branch: "beta"},
+			env:        map[string]string{"PINNACLE_BRANCH": "nightly"},
+			args:       []string{"-branch", "internal"},
+			key:        "branch",
+			wantValue:  "internal",
+			wantSource: "-branch",
+		},
+		{
+			name:       "setting without flag",
+			env:        map[string]string{"PINNACLE_JAVA_MAX_HEAP": "2G"},
+			key:        "java_max_heap",
+			wantValue:  "2G",
+			wantSource: "PINNACLE_JAVA_MAX_HEAP",
+		},
+		{
+			name:       "nested key",
+			env:        map[string]string{"PINNACLE_PROXY_URL": "http://proxy:3128"},
+			key:        "proxy.url",
+			wantValue:  "http://proxy:3128",
+			wantSource: "PINNACLE_PROXY_URL",
+		},
+		{
+			name:       "invalid flag value",
+			args:       []string{"-segments", "99"},
+			key:        "segments",
+			wantValue:  "99",
+			wantSource: "-segments",
+			wantErr:    "-segments: segments:",
+		},
+		{
+			name:       "invalid environment value",
+			env:        map[string]string{"PINNACLE_RETRIES": "many"},
+			key:        "retries",
+			wantValue:  "many",
+			wantSource: "PINNACLE_RETRIES",
+			wantErr:    "PINNACLE_RETRIES: retries:",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, s := range settings {
+				t.Setenv(s.env(), tt.env[s.env()])
+			}
+			p := &Pinnacle{}
+			if _, err := p.parseCommand(append([]string{"run"}, tt.args...)); err != nil {
+				t.Fatal(err)
+			}
+
+			_, resolved := p.resolveSettings(tt.file)
+			for _, r := range resolved {
+				if r.key != tt.key {
+					continue
+				}
+				if r.value != tt.wantValue || r.source != tt.wantSource {
+					t.Errorf("%s = %q from %s, want %q from %s", r.key, r.value, r.source, tt.wantValue, tt.wantSource)
+				}
+				switch {
+				case tt.wantErr == "" && r.err != nil:
+					t.Errorf("unexpected error: %v", r.err)
+				case tt.wantErr != "" && (r.err == nil || !strings.HasPrefix(r.err.Error(), tt.wantErr)):
+					t.Errorf("error = %v, want one starting with %q", r.err, tt.wantErr)
+				}
+				return
+			}
+			t.Fatalf("setting %q not resolved", tt.key)
+		})
+	}
+}
+
+func TestResolveSettingsApplies(t *testing.T) {
+	for _, s := range settings {
+		t.Setenv(s.env(), "")
+	}
+	t.Setenv("PINNACLE_JAVA_MAX_HEAP", "2G")
+
+	p := &Pinnacle{}
+	if _, err := p.parseCommand([]string{"run", "-segments", "4", "-launcher-version", "latest"}); err != nil {
+		t.Fatal(err)
+	}
+	cfg, _ := p.resolveSettings(Config{Branch: "beta", JavaMinHeap: "512M", LauncherVersion: "1.2.3"})
+
+	want := defaultConfig()
+	want.Branch, want.JavaMinHeap, want.JavaMaxHeap, want.Segments = "beta", "512M", "2G", 4
+	if cfg.Branch != want.Branch || cfg.JavaMinHeap != want.JavaMinHeap || cfg.JavaMaxHeap != want.JavaMaxHeap ||
+		cfg.Segments != want.Segments || cfg.LauncherVersion != "" || *cfg.Retries != *want.Retries {
+		t.Errorf("resolveSettings() = %+v, want %+v", cfg, want)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name             string
+		minHeap, maxHeap string
+		wantErr          string
+	}{
+		{name: "equal", minHeap: "256M", maxHeap: "256M"},
+		{name: "smaller", minHeap: "512M", maxHeap: "2G"},
+		{name: "binary units", minHeap: "1024M", maxHeap: "1G"},
+		{name: "kilobytes", minHeap: "1048576k", maxHeap: "1G"},
+		{name: "plain bytes", minHeap: "1073741824", maxHeap: "1g"},
+		{name: "min unset", maxHeap: "1G"},
+		{name: "larger", minHeap: "1025M", maxHeap: "1G", wantErr: "java_min_heap (1025M) is larger than java_max_heap (1G)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{JavaMinHeap: tt.minHeap, JavaMaxHeap: tt.maxHeap}
+			checkErrContains(t, c.validate(), tt.wantErr)
+		})
+	}
+}
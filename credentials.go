@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// credentials holds access tokens for private branches. It is kept in
+// credentials.json, readable only by the current user.
+type credentials struct {
+	Tokens map[string]string `json:"tokens"` // branch name to access token
+}
+
+var errUnauthorized = errors.New("unauthorized")
+
+// branchAccessError is returned when the metadata server refuses
+// access to a private branch.
+type branchAccessError struct {
+	branch   string
+	hadToken bool
+}
+
+func (e *branchAccessError) Error() string {
+	if e.hadToken {
+		return fmt.Sprintf("Your access to the %s branch has expired.\n\n"+
+			"Ask for a new access token and run \"pinnacle login -branch %s\".", e.branch, e.branch)
+	}
+	return fmt.Sprintf("The %s branch requires an access token.\n\n"+
+		"Run \"pinnacle login -branch %s\" to add one.", e.branch, e.branch)
+}
+
+func (p *Pinnacle) loadCredentials() (credentials, error) {
+	creds := credentials{Tokens: make(map[string]string)}
+
+	path := p.alpinePath("credentials.json")
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return creds, nil
+	}
+	if err != nil {
+		return creds, err
+	}
+	if info, err := os.Stat(path); err == nil && info.Mode().Perm()&0o077 != 0 {
+		_ = os.Chmod(path, 0o600) // tighten permissions loosened by hand or a backup tool
+	}
+
+	if err = json.Unmarshal(data, &creds); err != nil {
+		return creds, fmt.Errorf("invalid credentials.json: %w", err)
+	}
+	if creds.Tokens == nil {
+		creds.Tokens = make(map[string]string)
+	}
+	return creds, nil
+}
+
+func (p *Pinnacle) saveCredentials(creds credentials) error {
+	data, err := json.MarshalIndent(creds, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(p.alpinePath(), os.ModePerm); err != nil {
+		return err
+	}
+	path := p.alpinePath("credentials.json")
+	if err = os.WriteFile(path+".tmp", data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// loadToken sets p.token for the current branch. PINNACLE_TOKEN
+// overrides the saved token, e.g. for automated test machines.
+func (p *Pinnacle) loadToken() error {
+	if token := os.Getenv("PINNACLE_TOKEN"); token != "" {
+		p.token = token
+		return nil
+	}
+	creds, err := p.loadCredentials()
+	if err != nil {
+		return err
+	}
+	p.token = creds.Tokens[p.branch]
+	return nil
+}
+
+// tokenDomains are the domains whose hosts may receive the access token:
+// the metadata server and the servers hosting the artifacts it lists.
+var tokenDomains = []string{"alpineclient.com"}
+
+// authHeader returns the headers for a request to rawURL scoped to the
+// current branch. The token is only sent over https to the metadata
+// server or a host within tokenDomains, never to third-party mirrors;
+// otherwise it returns nil.
+func (p *Pinnacle) authHeader(rawURL string) http.Header {
+	if p.token == "" {
+		return nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" || !tokenHost(u) {
+		return nil
+	}
+	return http.Header{"Authorization": {"Bearer " + p.token}}
+}
+
+// tokenHost reports whether u's host may receive the access token.
+// Only the default https port is accepted, besides the metadata server's.
+func tokenHost(u *url.URL) bool {
+	if u.Host == metadataHost() {
+		return true
+	}
+	if u.Port() != "" && u.Port() != "443" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, domain := range tokenDomains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// metadataHost returns the host, with any port, of MetadataURL.
+func metadataHost() string {
+	u, err := url.Parse(MetadataURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// branchAccess turns a request for branch content refused by the
+// metadata server into a branchAccessError. Artifact hosts don't decide
+// branch access, so their refusals are ordinary download errors.
+func (p *Pinnacle) branchAccess(err error) error {
+	if errors.Is(err, errUnauthorized) {
+		return &branchAccessError{branch: p.branch, hadToken: p.token != ""}
+	}
+	return err
+}
+
+// Login reads an access token for the current branch from in, checks
+// that the metadata server accepts it and saves it.
+func (p *Pinnacle) Login(ctx context.Context, in io.Reader, out io.Writer) error {
+	_, _ = fmt.Fprintf(out, "Access token for the %s branch: ", p.branch)
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	token := strings.TrimSpace(line)
+	if token == "" {
+		return errors.New("no access token given")
+	}
+
+	p.token = token
+	endpoint := MetadataURL + "/pinnacle?branch=" + p.branch
+	if _, err = p.fetchMetadata(ctx, endpoint, p.authHeader(endpoint)); err != nil {
+		if errors.Is(err, errUnauthorized) {
+			return fmt.Errorf("the access token was rejected for the %s branch", p.branch)
+		}
+		return err
+	}
+
+	creds, err := p.loadCredentials()
+	if err != nil {
+		return err
+	}
+	creds.Tokens[p.branch] = token
+	if err = p.saveCredentials(creds); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(out, "Saved access token for the %s branch.\n", p.branch)
+	return nil
+}
+
+// Logout removes the saved access token for the current branch.
+func (p *Pinnacle) Logout(out io.Writer) error {
+	creds, err := p.loadCredentials()
+	if err != nil {
+		return err
+	}
+	if _, found := creds.Tokens[p.branch]; !found {
+		_, _ = fmt.Fprintf(out, "No access token saved for the %s branch.\n", p.branch)
+		return nil
+	}
+	delete(creds.Tokens, p.branch)
+	if err = p.saveCredentials(creds); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(out, "Removed access token for the %s branch.\n", p.branch)
+	return nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAuthHeader(t *testing.T) {
+	tests := []struct {
+		name  string
+		token string
+		url   string
+		want  bool
+	}{
+		{name: "metadata server", token: "secret", url: MetadataURL + "/pinnacle?branch=internal", want: true},
+		{name: "no token", url: MetadataURL + "/pinnacle?branch=internal"},
+		{name: "plain http", token: "secret", url: "http://" + metadataHost() + "/pinnacle"},
+		{name: "artifact host", token: "secret", url: "https://cdn.alpineclient.com/launcher.jar", want: true},
+		{name: "artifact host with https port", token: "secret", url: "https://cdn.alpineclient.com:443/launcher.jar", want: true},
+		{name: "artifact over plain http", token: "secret", url: "http://cdn.alpineclient.com/launcher.jar"},
+		{name: "other host", token: "secret", url: "https://cdn.example.com/launcher.jar"},
+		{name: "suffix lookalike", token: "secret", url: "https://evilalpineclient.com/launcher.jar"},
+		{name: "lookalike host", token: "secret", url: "https://" + metadataHost() + ".example.com/launcher.jar"},
+		{name: "other port", token: "secret", url: "https://" + metadataHost() + ":8443/launcher.jar"},
+		{name: "invalid url", token: "secret", url: "://"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := (&Pinnacle{token: tt.token}).authHeader(tt.url)
+			if got := header.Get("Authorization") != ""; got != tt.want {
+				t.Errorf("authHeader(%q) = %v, want Authorization %v", tt.url, header, tt.want)
+			}
+			if tt.want && header.Get("Authorization") != "Bearer "+tt.token {
+				t.Errorf("Authorization = %q, want a bearer token", header.Get("Authorization"))
+			}
+		})
+	}
+}
+
+// serveAs makes httpClient send requests for any host to srv,
+// skipping certificate verification, until the test ends.
+func serveAs(t *testing.T, srv *httptest.Server) {
+	t.Helper()
+	transport := httpClient.Transport.(*http.Transport)
+	dial, tlsConfig := transport.DialContext, transport.TLSClientConfig
+	t.Cleanup(func() { transport.DialContext, transport.TLSClientConfig = dial, tlsConfig })
+
+	transport.DialContext = func(ctx context.Context, network string, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, srv.Listener.Addr().String())
+	}
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true} //nolint:gosec // test server
+}
+
+func TestArtifactDownloadToken(t *testing.T) {
+	hash, err := hashReader(strings.NewReader("mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte("mod"))
+	}))
+	t.Cleanup(srv.Close)
+	serveAs(t, srv)
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "token sent", token: "secret"},
+		{name: "refused by artifact host", token: "expired", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := testPinnacle(t.TempDir())
+			p.branch, p.token = "internal", tt.token
+			p.config.Retries = new(int)
+			c := &manifestComponent{p: p, desc: componentDescriptor{Name: "mods", Target: "mods"}}
+
+			f := ManifestFile{Path: "a.jar", URL: "https://cdn.alpineclient.com/mods/a.jar", Hash: hash, Size: 3}
+			err := c.installFile(context.Background(), f)
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("installFile() error = %v", err)
+				}
+				return
+			}
+
+			var accessErr *branchAccessError
+			if !errors.Is(err, errUnauthorized) || errors.As(err, &accessErr) {
+				t.Errorf("installFile() error = %v, want an ordinary unauthorized download error", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// portableMarker next to the executable switches Pinnacle to portable
+// mode, keeping everything in portableDir beside it, e.g. on a USB stick.
+const (
+	portableMarker = "pinnacle.portable"
+	portableDir    = "alpineclient-data"
+)
+
+// resolveDirs picks the data directory: the -data-dir flag, then
+// PINNACLE_DATA_DIR, then portable mode, then the platform default.
+// Logs, state and downloaded archives are kept in the data directory,
+// except in the Linux default where they follow the XDG base directories.
+// Every directory is absolute.
+func (p *Pinnacle) resolveDirs() error {
+	dir, source := p.opts.dataDir, "-data-dir"
+	if dir == "" {
+		dir, source = os.Getenv("PINNACLE_DATA_DIR"), "PINNACLE_DATA_DIR"
+	}
+	if dir == "" {
+		dir, source = portableDataDir(), "portable mode"
+	}
+
+	if dir == "" && p.os == Linux {
+		return p.resolveXDGDirs()
+	}
+	if dir == "" {
+		var err error
+		if dir, err = defaultDataDir(p.os); err != nil {
+			return err
+		}
+	}
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("%s: %w", source, err)
+	}
+	if info, err := os.Stat(abs); err == nil && !info.IsDir() {
+		return fmt.Errorf("%s: %s is not a directory", source, abs)
+	}
+	p.dataDir, p.cacheDir, p.stateDir = abs, abs, abs
+	return nil
+}
+
+// cachePath is like alpinePath, for files that can be downloaded again.
+func (p *Pinnacle) cachePath(subs ...string) string {
+	return filepath.Join(append([]string{p.cacheDir}, subs...)...)
+}
+
+// statePath is like alpinePath, for logs and run history.
+func (p *Pinnacle) statePath(subs ...string) string {
+	return filepath.Join(append([]string{p.stateDir}, subs...)...)
+}
+
+// portableDataDir returns the portable data directory if the marker
+// file exists next to the executable.
+func portableDataDir() string {
+	exe, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	dir := filepath.Dir(exe)
+	if !fileExists(filepath.Join(dir, portableMarker)) {
+		return ""
+	}
+	return filepath.Join(dir, portableDir)
+}
+
+// defaultDataDir returns the platform's data directory:
+//
+// Windows - %AppData%\.alpineclient
+// Mac - $HOME/Library/Application Support/alpineclient
+//
+// Linux follows the XDG base directories instead, see resolveXDGDirs.
+//
+// note: The missing '.' for macOS is intentional.
+func defaultDataDir(system OperatingSystem) (string, error) {
+	var base string
+	var dirs []string
+
+	switch system {
+	case Windows:
+		base = os.Getenv("AppData")
+		dirs = []string{".alpineclient"}
+	case Mac:
+		base = os.Getenv("HOME")
+		dirs = []string{"Library", "Application Support", "alpineclient"}
+	default:
+		return "", fmt.Errorf("unsupported operating system %q", system)
+	}
+
+	if base == "" || !filepath.IsAbs(base) {
+		variable := "HOME"
+		if system == Windows {
+			variable = "AppData"
+		}
+		return "", errors.New("unable to find a data directory: " + variable +
+			" is not set to an absolute path, use -data-dir or PINNACLE_DATA_DIR")
+	}
+	return filepath.Join(append([]string{base}, dirs...)...), nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveDirs(t *testing.T) {
+	root := t.TempDir()
+	home := filepath.Join(root, "home")
+	notDir := filepath.Join(root, "file")
+	if err := os.WriteFile(notDir, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		os        OperatingSystem
+		flag      string
+		env       map[string]string
+		wantData  string
+		wantCache string
+		wantState string
+		wantErr   string
+	}{
+		{
+			name:     "flag",
+			os:       Linux,
+			flag:     filepath.Join(root, "flag"),
+			env:      map[string]string{"PINNACLE_DATA_DIR": filepath.Join(root, "env")},
+			wantData: filepath.Join(root, "flag"),
+		},
+		{
+			name:     "environment",
+			os:       Linux,
+			env:      map[string]string{"PINNACLE_DATA_DIR": filepath.Join(root, "env")},
+			wantData: filepath.Join(root, "env"),
+		},
+		{
+			name:     "relative flag",
+			os:       Windows,
+			flag:     "data",
+			wantData: mustAbs(t, "data"),
+		},
+		{
+			name:    "flag is a file",
+			os:      Linux,
+			flag:    notDir,
+			wantErr: "-data-dir: " + notDir + " is not a directory",
+		},
+		{
+			name:      "linux default",
+			os:        Linux,
+			wantData:  filepath.Join(home, ".local", "share", "alpineclient"),
+			wantCache: filepath.Join(home, ".cache", "alpineclient"),
+			wantState: filepath.Join(home, ".local", "state", "alpineclient"),
+		},
+		{
+			name: "linux xdg variables",
+			os:   Linux,
+			env: map[string]string{
+				"XDG_DATA_HOME":  filepath.Join(root, "data"),
+				"XDG_CACHE_HOME": "relative/is/ignored",
+				"XDG_STATE_HOME": filepath.Join(root, "state"),
+			},
+			wantData:  filepath.Join(root, "data", "alpineclient"),
+			wantCache: filepath.Join(home, ".cache", "alpineclient"),
+			wantState: filepath.Join(root, "state", "alpineclient"),
+		},
+		{
+			name:     "mac default",
+			os:       Mac,
+			wantData: filepath.Join(home, "Library", "Application Support", "alpineclient"),
+		},
+		{
+			name:     "windows default",
+			os:       Windows,
+			env:      map[string]string{"AppData": filepath.Join(root, "AppData")},
+			wantData: filepath.Join(root, "AppData", ".alpineclient"),
+		},
+		{
+			name:    "windows without AppData",
+			os:      Windows,
+			wantErr: "AppData is not set",
+		},
+		{
+			name:    "linux without HOME",
+			os:      Linux,
+			env:     map[string]string{"HOME": "relative"},
+			wantErr: "HOME is not set",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"PINNACLE_DATA_DIR", "XDG_DATA_HOME", "XDG_CACHE_HOME", "XDG_STATE_HOME", "AppData"} {
+				t.Setenv(name, tt.env[name])
+			}
+			t.Setenv("HOME", home)
+			if v, ok := tt.env["HOME"]; ok {
+				t.Setenv("HOME", v)
+			}
+
+			p := &Pinnacle{os: tt.os}
+			p.opts.dataDir = tt.flag
+			err := p.resolveDirs()
+			checkErrContains(t, err, tt.wantErr)
+			if err != nil {
+				return
+			}
+
+			// Outside the Linux default everything shares the data directory.
+			if tt.wantCache == "" {
+				tt.wantCache, tt.wantState = tt.wantData, tt.wantData
+			}
+			if p.dataDir != tt.wantData || p.cacheDir != tt.wantCache || p.stateDir != tt.wantState {
+				t.Errorf("resolveDirs() = data %s, cache %s, state %s, want %s, %s, %s",
+					p.dataDir, p.cacheDir, p.stateDir, tt.wantData, tt.wantCache, tt.wantState)
+			}
+		})
+	}
+}
+
+func mustAbs(t *testing.T, path string) string {
+	t.Helper()
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return abs
+}
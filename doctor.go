@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// diagnosis is the result of a single doctor check. A warning
+// points out something the next run fixes by itself.
+type diagnosis struct {
+	name   string
+	detail string
+	ok     bool
+	warn   bool
+}
+
+// Doctor runs a series of checks covering the data directory,
+// configuration, network, clock and installed components.
+func (p *Pinnacle) Doctor(ctx context.Context, w io.Writer) error {
+	results := []diagnosis{
+		{name: "system", ok: true, detail: fmt.Sprintf("%s/%s, pinnacle %s", p.os, p.arch, displayVersion())},
+		p.checkDataDir(),
+		p.checkConfig(),
+		{name: "proxy", ok: true, detail: p.proxySource},
+	}
+
+	network := p.checkMetadataServer(ctx)
+	results = append(results, network)
+	if network.ok {
+		results = append(results, p.checkClock())
+	}
+	results = append(results, p.checkJavaRuns(ctx))
+
+	if network.ok {
+		if err := p.registerManifestComponents(ctx); err != nil {
+			results = append(results, diagnosis{name: "components", detail: err.Error()})
+		}
+		components, err := orderComponents(p.components)
+		if err != nil {
+			return err
+		}
+		for _, c := range components {
+			cp := p.planComponent(ctx, c)
+			results = append(results, diagnosis{
+				name:   c.Name(),
+				ok:     cp.Status == "up-to-date",
+				warn:   cp.Status == "install",
+				detail: strings.ReplaceAll(cp.Status, "-", " ") + " " + cp.Reason,
+			})
+		}
+	}
+
+	failed := 0
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, d := range results {
+		mark := "OK"
+		switch {
+		case d.ok:
+		case d.warn:
+			mark = "WARN"
+		default:
+			mark = "FAIL"
+			failed++
+		}
+		_, _ = fmt.Fprintf(tw, "[%s]\t%s\t%s\n", mark, d.name, strings.TrimSpace(d.detail))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d check(s) failed", failed)
+	}
+	return nil
+}
+
+func (p *Pinnacle) checkDataDir() diagnosis {
+	d := diagnosis{name: "data directory", detail: p.alpinePath()}
+
+	if !fileExists(p.alpinePath()) {
+		d.ok = true
+		d.detail += " (not created yet)"
+		return d
+	}
+
+	probe, err := os.CreateTemp(p.alpinePath(), ".doctor-*")
+	if err != nil {
+		d.detail += ": not writable: " + err.Error()
+		return d
+	}
+	_ = probe.Close()
+	_ = os.Remove(probe.Name())
+
+	d.ok = true
+	return d
+}
+
+func (p *Pinnacle) checkConfig() diagnosis {
+	if _, err := p.loadConfig(); err != nil {
+		return diagnosis{name: "config", detail: err.Error()}
+	}
+	return diagnosis{name: "config", ok: true, detail: p.alpinePath("config.json")}
+}
+
+// checkMetadataServer makes a single request to the metadata server,
+// explaining certificate failures the same way a run would.
+func (p *Pinnacle) checkMetadataServer(ctx context.Context) diagnosis {
+	d := diagnosis{name: "metadata server", detail: MetadataURL}
+
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Second)
+	defer cancel()
+
+	endpoint := MetadataURL + "/pinnacle?branch=" + url.QueryEscape(p.branch)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		d.detail = err.Error()
+		return d
+	}
+	req.Header.Set("User-Agent", fmt.Sprintf("Pinnacle/%s (%s; %s)", version, p.os, p.arch))
+	for key, values := range p.authHeader(endpoint) {
+		req.Header[key] = values
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		if certErr := p.certificateError(ctx, req.URL.Host, err); certErr != nil {
+			err = certErr
+		}
+		d.detail = err.Error()
+		return d
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	p.recordClockSkew(resp)
+
+	if err = checkCaptivePortal(endpoint, resp, true); err != nil {
+		d.detail = err.Error()
+		return d
+	}
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		d.detail = (&branchAccessError{branch: p.branch, hadToken: p.token != ""}).Error()
+		return d
+	}
+	if resp.StatusCode != http.StatusOK {
+		d.detail = fmt.Sprintf("%s: status code %d", MetadataURL, resp.StatusCode)
+		return d
+	}
+
+	d.ok = true
+	return d
+}
+
+func (p *Pinnacle) checkClock() diagnosis {
+	if !p.clockSkewKnown.Load() {
+		return diagnosis{name: "clock", ok: true, detail: "server time unavailable"}
+	}
+	skew := time.Duration(p.clockSkew.Load()).Round(time.Second)
+	return diagnosis{
+		name:   "clock",
+		ok:     skew.Abs() < maxClockSkew,
+		detail: fmt.Sprintf("off by %s from server time", skew),
+	}
+}
+
+// checkJavaRuns runs "java -version" from the installed runtime.
+func (p *Pinnacle) checkJavaRuns(ctx context.Context) diagnosis {
+	d := diagnosis{name: "java runtime"}
+
+	path := p.alpinePath("jre", "17", "extracted", "bin", p.os.javaExecutable())
+	if !fileExists(path) {
+		d.warn = true
+		d.detail = "not installed yet"
+		return d
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var out bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, "-version")
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		d.detail = "failed to run: " + err.Error()
+		return d
+	}
+
+	d.ok = true
+	d.detail, _, _ = strings.Cut(out.String(), "\n")
+	return d
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// componentPlan describes what a run would do for one component.
+type componentPlan struct {
+	Name      string            `json:"name"`
+	Status    string            `json:"status"` // "up-to-date", "install" or "error"
+	Reason    string            `json:"reason,omitempty"`
+	Downloads []plannedDownload `json:"downloads,omitempty"`
+	Removes   []string          `json:"removes,omitempty"`
+}
+
+type plannedDownload struct {
+	URL  string `json:"url"`
+	Path string `json:"path,omitempty"`
+	Size uint32 `json:"size"`
+}
+
+// installPlan is the output of a dry run.
+type installPlan struct {
+	Branch           string          `json:"branch"`
+	DataDir          string          `json:"data_dir"`
+	Components       []componentPlan `json:"components"`
+	CleanupOnFailure []string        `json:"cleanup_on_failure"`
+}
+
+// pendingLister is implemented by components that can list exactly
+// which of their files an install would download and remove.
+type pendingLister interface {
+	pending(meta *MetadataResponse) ([]ManifestFile, []string)
+}
+
+// Plan runs every metadata fetch and local check without writing to
+// disk or starting the launcher, and prints what a run would do.
+func (p *Pinnacle) Plan(w io.Writer, format string) error {
+	ctx := context.Background()
+
+	if p.lock == nil {
+		if err := p.checkBranch(ctx); err != nil {
+			return err
+		}
+	}
+	if err := p.registerManifestComponents(ctx); err != nil {
+		return err
+	}
+	components, err := orderComponents(p.components)
+	if err != nil {
+		return err
+	}
+
+	plan := installPlan{
+		Branch:  p.branch,
+		DataDir: p.alpinePath(),
+	}
+	for _, c := range components {
+		plan.Components = append(plan.Components, p.planComponent(ctx, c))
+		plan.CleanupOnFailure = append(plan.CleanupOnFailure, c.Paths()...)
+	}
+
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(plan)
+	}
+	return plan.print(w)
+}
+
+func (p *Pinnacle) planComponent(ctx context.Context, c Component) componentPlan {
+	cp := componentPlan{Name: c.Name(), Status: "up-to-date"}
+
+	meta, err := c.Check(ctx)
+	switch {
+	case err == nil:
+		return cp
+	case !errors.Is(err, errNeedsInstall):
+		cp.Status = "error"
+		cp.Reason = err.Error()
+		return cp
+	}
+
+	cp.Status = "install"
+	cp.Reason = err.Error()
+
+	if lister, ok := c.(pendingLister); ok {
+		files, removes := lister.pending(meta)
+		for _, f := range files {
+			cp.Downloads = append(cp.Downloads, plannedDownload{URL: f.URL, Path: f.Path, Size: f.Size})
+		}
+		cp.Removes = removes
+		return cp
+	}
+
+	cp.Downloads = []plannedDownload{{URL: meta.URL, Size: meta.Size}}
+	for _, path := range c.Paths() {
+		if fileExists(path) {
+			cp.Removes = append(cp.Removes, path) // replaced by the install
+		}
+	}
+	return cp
+}
+
+func (plan *installPlan) print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "Dry run for branch %s in %s\n\n", plan.Branch, plan.DataDir)
+
+	for _, c := range plan.Components {
+		var total int64
+		for _, d := range c.Downloads {
+			total += int64(d.Size)
+		}
+
+		switch c.Status {
+		case "install":
+			_, _ = fmt.Fprintf(tw, "%s\twould download %d file(s), %s\t%s\n",
+				c.Name, len(c.Downloads), formatBytes(total), c.Reason)
+		case "error":
+			_, _ = fmt.Fprintf(tw, "%s\tcheck failed\t%s\n", c.Name, c.Reason)
+		default:
+			_, _ = fmt.Fprintf(tw, "%s\tup to date\n", c.Name)
+		}
+		for _, d := range c.Downloads {
+			_, _ = fmt.Fprintf(tw, "\t  get %s\t%s\n", d.URL, formatBytes(int64(d.Size)))
+		}
+		for _, path := range c.Removes {
+			_, _ = fmt.Fprintf(tw, "\t  remove %s\n", path)
+		}
+	}
+
+	_, _ = fmt.Fprintln(tw, "\nIf any step fails, cleanup would remove:")
+	for _, path := range plan.CleanupOnFailure {
+		_, _ = fmt.Fprintf(tw, "  %s\n", path)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// outdatedComponent is a single-download component that needs installing.
+type outdatedComponent struct {
+	fakeComponent
+	meta  *MetadataResponse
+	paths []string
+}
+
+func (c *outdatedComponent) Check(context.Context) (*MetadataResponse, error) {
+	return c.meta, fmt.Errorf("%w: missing launcher", errNeedsInstall)
+}
+
+func (c *outdatedComponent) Paths() []string { return c.paths }
+
+func TestPlan(t *testing.T) {
+	dir := t.TempDir()
+	p := testPinnacle(dir)
+	p.branch = "beta"
+
+	jar := filepath.Join(dir, "launcher.jar")
+	if err := os.WriteFile(jar, []byte("old"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	mods := &manifestComponent{p: p, desc: componentDescriptor{Name: "mods", Target: "mods", Requires: []string{"launcher"}}}
+	if err := os.MkdirAll(filepath.Join(dir, "mods"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := mods.writeInstalled(map[string]string{"old.jar": "1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A lockfile keeps every check offline.
+	p.lock = map[string]*MetadataResponse{
+		"mods": {Files: []ManifestFile{{Path: "a.jar", URL: "https://cdn.example.com/a.jar", Size: 2000}}},
+	}
+	p.manifestsRegistered = true
+	p.register(
+		&fakeComponent{name: "java"},
+		&fakeComponent{name: "sounds", err: errors.New("internet failure")},
+		&outdatedComponent{
+			fakeComponent: fakeComponent{name: "launcher", requires: []string{"java"}},
+			meta:          &MetadataResponse{URL: "https://cdn.example.com/launcher.jar", Size: 1000},
+			paths:         []string{jar, filepath.Join(dir, "launcher-beta.jar")},
+		},
+		mods,
+	)
+
+	var out bytes.Buffer
+	if err := p.Plan(&out, "json"); err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	var plan installPlan
+	if err := json.Unmarshal(out.Bytes(), &plan); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []componentPlan{
+		{Name: "java", Status: "up-to-date"},
+		{Name: "sounds", Status: "error", Reason: "internet failure"},
+		{
+			Name:      "launcher",
+			Status:    "install",
+			Reason:    "needs install: missing launcher",
+			Downloads: []plannedDownload{{URL: "https://cdn.example.com/launcher.jar", Size: 1000}},
+			Removes:   []string{jar},
+		},
+		{
+			Name:      "mods",
+			Status:    "install",
+			Reason:    "needs install: 1 file(s) to download, 1 to remove",
+			Downloads: []plannedDownload{{URL: "https://cdn.example.com/a.jar", Path: "a.jar", Size: 2000}},
+			Removes:   []string{"old.jar"},
+		},
+	}
+	if plan.Branch != "beta" || plan.DataDir != dir {
+		t.Errorf("plan for %s in %s, want beta in %s", plan.Branch, plan.DataDir, dir)
+	}
+	if !slices.EqualFunc(plan.Components, want, componentPlansEqual) {
+		t.Errorf("components = %+v, want %+v", plan.Components, want)
+	}
+	if !slices.Contains(plan.CleanupOnFailure, filepath.Join(dir, "mods", "old.jar")) {
+		t.Errorf("cleanup = %q, want it to include the installed mod", plan.CleanupOnFailure)
+	}
+
+	out.Reset()
+	if err := plan.print(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"Dry run for branch beta in " + dir,
+		"java      up to date",
+		"sounds    check failed",
+		"launcher  would download 1 file(s), 1.0 kB",
+		"get https://cdn.example.com/a.jar",
+		"remove old.jar",
+		"If any step fails, cleanup would remove:",
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("text plan is missing %q:\n%s", line, out.String())
+		}
+	}
+}
+
+func componentPlansEqual(a componentPlan, b componentPlan) bool {
+	return a.Name == b.Name && a.Status == b.Status && a.Reason == b.Reason &&
+		slices.Equal(a.Downloads, b.Downloads) && slices.Equal(a.Removes, b.Removes)
+}
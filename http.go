@@ -32,13 +32,47 @@
 	},
 }
 
-func (p *Pinnacle) getFromURL(ctx context.Context, url string) (*http.Response, error) {
-	const maxAttempts = 4
+// configureTimeouts sets how long httpClient waits to connect
+// and for response headers.
+func configureTimeouts(connect, response string) error {
+	transport, ok := httpClient.Transport.(*http.Transport)
+	if !ok {
+		return errors.New("unexpected http transport")
+	}
+
+	connectTimeout, err := time.ParseDuration(connect)
+	if err != nil {
+		return err
+	}
+	responseTimeout, err := time.ParseDuration(response)
+	if err != nil {
+		return err
+	}
+
+	transport.DialContext = (&net.Dialer{
+		Timeout:   connectTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.ResponseHeaderTimeout = responseTimeout
+	return nil
+}
+
+// request performs a GET request with retries and exponential backoff,
+// returning the first response whose status code matches want.
+func (p *Pinnacle) request(ctx context.Context, url string, header http.Header, want int) (*http.Response, error) {
+	maxAttempts := 4
+	if p.config.Retries != nil {
+		maxAttempts = *p.config.Retries + 1
+	}
 	var statusCode int
 
 	for i := range maxAttempts {
 		if i > 0 {
-			<-time.After(time.Second * time.Duration(2<<i)) // Exponential backoff
+			select {
+			case <-time.After(time.Second * time.Duration(2<<i)): // Exponential backoff
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
 		}
 		p.Breadcrumb(ctx, fmt.Sprintf("[%d] making request to %s", i+1, url))
 
@@ -46,19 +80,34 @@ func (p *Pinnacle) getFromURL(ctx context.Context, url string) (*http.Response,
 		if err != nil {
 			return nil, err
 		}
+		for key, values := range header {
+			request.Header[key] = values
+		}
 		request.Header.Set("User-Agent", fmt.Sprintf("Pinnacle/%s (%s; %s)", version, p.os, p.arch))
 
 		response, err := httpClient.Do(request)
 		if err != nil {
 			p.client.Breadcrumb(ctx, fmt.Sprintf("[%d] request error: %v", i+1, err), slog.LevelError)
+			if certErr := p.certificateError(ctx, request.URL.Host, err); certErr != nil {
+				return nil, certErr // retrying won't help
+			}
 			continue
 		}
+		p.recordClockSkew(response)
 
 		statusCode = response.StatusCode
 		p.Breadcrumb(ctx, fmt.Sprintf("[%d] status code: %d", i+1, statusCode))
-		if statusCode == http.StatusOK {
+		if statusCode == want {
 			return response, nil
 		}
+		if statusCode == http.StatusNotFound {
+			p.CaptureErr(ctx, response.Body.Close())
+			return nil, fmt.Errorf("%w: %s", errNotFound, url) // retrying won't help
+		}
+		if statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden {
+			p.CaptureErr(ctx, response.Body.Close())
+			return nil, fmt.Errorf("%w: %s", errUnauthorized, url) // retrying won't help
+		}
 
 		err = response.Body.Close()
 		if err != nil {
@@ -68,8 +117,16 @@ func (p *Pinnacle) getFromURL(ctx context.Context, url string) (*http.Response,
 	return nil, errors.New("internet failure")
 }
 
-func (p *Pinnacle) downloadFile(ctx context.Context, url string, path string, pt *ui.ProgressiveTask) error {
-	resp, err := p.getFromURL(ctx, url)
+var (
+	errNotFound          = errors.New("not found")
+	errTruncatedDownload = errors.New("truncated download")
+	errOversizedDownload = errors.New("download exceeds expected size")
+)
+
+func (p *Pinnacle) downloadFile(
+	ctx context.Context, url string, header http.Header, path string, size uint32, pt *ui.ProgressiveTask,
+) error {
+	resp, err := p.request(ctx, url, header, http.StatusOK)
 	if err != nil {
 		return err
 	}
@@ -77,6 +134,11 @@ func (p *Pinnacle) downloadFile(ctx context.Context, url string, path string, pt
 		p.CaptureErr(ctx, resp.Body.Close())
 	}()
 
+	err = checkCaptivePortal(url, resp, false)
+	if err != nil {
+		return err
+	}
+
 	file, err := os.Create(path)
 	if err != nil {
 		return err
@@ -85,17 +147,48 @@ func (p *Pinnacle) downloadFile(ctx context.Context, url string, path string, pt
 		p.CaptureErr(ctx, file.Close())
 	}()
 
-	err = copyResponseWithProgress(file, resp, pt)
+	err = p.copyResponseWithProgress(ctx, file, resp, int64(size), pt)
 	return err
 }
 
-func copyResponseWithProgress(dst io.Writer, resp *http.Response, pt *ui.ProgressiveTask) error {
+// copyResponseWithProgress copies the response body to dst while
+// reporting progress to pt.
+//
+// expected is the size advertised by metadata (0 if unknown). It is
+// used for progress when the server does not send a Content-Length and
+// to reject bodies that are shorter or longer than advertised.
+//
+// Reads are throttled by p.limiter when a rate limit is configured.
+func (p *Pinnacle) copyResponseWithProgress(
+	ctx context.Context, dst io.Writer, resp *http.Response, expected int64, pt *ui.ProgressiveTask,
+) error {
+	total := resp.ContentLength
+	switch {
+	case total < 0:
+		total = expected
+	case expected > 0 && total > expected:
+		return fmt.Errorf("%w: server sent %d bytes, expected %d", errOversizedDownload, total, expected)
+	case expected > 0 && total < expected:
+		return fmt.Errorf("%w: server sent %d bytes, expected %d", errTruncatedDownload, total, expected)
+	}
+
+	stats := p.newTransferStats(total)
+	if pt != nil {
+		defer pt.SetNote("") // speed and ETA don't apply to whatever the task shows next
+	}
+
 	var written int64
 	var err error
 	buf := make([]byte, 32*1024)
 	src := resp.Body
 	for {
 		nr, er := src.Read(buf)
+		if lw := p.limiter.wait(ctx, nr); lw != nil {
+			return lw
+		}
+		if total > 0 && written+int64(nr) > total {
+			return fmt.Errorf("%w: received more than %d bytes", errOversizedDownload, total)
+		}
 		if nr > 0 {
 			nw, ew := dst.Write(buf[0:nr])
 			if nw < 0 || nr < nw {
@@ -113,8 +206,9 @@ func copyResponseWithProgress(dst io.Writer, resp *http.Response, pt *ui.Progres
 				err = io.ErrShortWrite
 				break
 			}
-			if pt != nil {
-				pt.UpdateProgress(float64(written) / float64(resp.ContentLength))
+			if pt != nil && total > 0 {
+				pt.SetNote(stats.note(written))
+				pt.UpdateProgress(float64(written) / float64(total))
 			}
 		}
 		if er != nil {
@@ -124,6 +218,9 @@ func copyResponseWithProgress(dst io.Writer, resp *http.Response, pt *ui.Progres
 			break
 		}
 	}
+	if err == nil && total > 0 && written != total {
+		err = fmt.Errorf("%w: received %d of %d bytes", errTruncatedDownload, written, total)
+	}
 	return err
 }
 
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCopyResponseWithProgress(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		contentLength int64
+		expected      int64
+		wantErr       error
+	}{
+		{name: "exact", body: "hello", contentLength: 5, expected: 5},
+		{name: "unknown length", body: "hello", contentLength: -1, expected: 5},
+		{name: "nothing expected", body: "hello", contentLength: -1, expected: 0},
+		{name: "header larger than expected", body: "hello world", contentLength: 11, expected: 5, wantErr: errOversizedDownload},
+		{name: "header smaller than expected", body: "hel", contentLength: 3, expected: 5, wantErr: errTruncatedDownload},
+		{name: "body longer than header", body: "hello world", contentLength: 5, expected: 0, wantErr: errOversizedDownload},
+		{name: "body shorter than header", body: "hel", contentLength: 5, expected: 5, wantErr: errTruncatedDownload},
+		{name: "unknown length too long", body: "hello world", contentLength: -1, expected: 5, wantErr: errOversizedDownload},
+		{name: "unknown length too short", body: "hel", contentLength: -1, expected: 5, wantErr: errTruncatedDownload},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Pinnacle{}
+			resp := &http.Response{
+				ContentLength: tt.contentLength,
+				Body:          io.NopCloser(strings.NewReader(tt.body)),
+			}
+
+			var dst bytes.Buffer
+			err := p.copyResponseWithProgress(context.Background(), &dst, resp, tt.expected, nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("copyResponseWithProgress() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && dst.String() != tt.body {
+				t.Errorf("copied %q, want %q", dst.String(), tt.body)
+			}
+		})
+	}
+}
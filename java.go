@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/alpine-client/pinnacle/ui"
+)
+
+// javaComponent is the Java runtime the launcher runs on.
+type javaComponent struct {
+	p *Pinnacle
+
+	// installed is set once Install extracted the runtime from an archive
+	// whose checksum downloadLargeFile verified, so its files were just hashed.
+	installed bool
+}
+
+func (*javaComponent) Name() string {
+	return "java"
+}
+
+func (*javaComponent) Requires() []string {
+	return nil
+}
+
+func (c *javaComponent) Check(ctx context.Context) (*MetadataResponse, error) {
+	return c.p.checkJava(ctx)
+}
+
+func (c *javaComponent) Install(ctx context.Context, meta *MetadataResponse) error {
+	if err := c.p.downloadJava(ctx, meta); err != nil {
+		return err
+	}
+	c.installed = true
+	return nil
+}
+
+func (c *javaComponent) Verify(ctx context.Context, meta *MetadataResponse) error {
+	pt := ui.NewProgressTask("Verifying Java runtime...")
+	defer pt.Done()
+
+	err := c.p.verifyJava(ctx, meta, pt)
+	if err == nil && !c.installed {
+		err = c.p.verifyJavaFiles(ctx)
+	}
+	if err != nil {
+		return fmt.Errorf("java runtime %s failed verification: %w", meta.Name, err)
+	}
+	return nil
+}
+
+func (c *javaComponent) Paths() []string {
+	return []string{c.p.alpinePath("jre", "17")}
+}
+
+// verifyJavaFiles re-hashes every file of the extracted runtime against
+// the hashes recorded in version.json when it was installed. Runtimes
+// installed before hashes were recorded can't be checked, so they need
+// to be installed again.
+func (p *Pinnacle) verifyJavaFiles(ctx context.Context) error {
+	data, err := os.ReadFile(p.alpinePath("jre", "17", "version.json"))
+	if err != nil {
+		return err
+	}
+
+	var manifest JavaManifest
+	if err = json.Unmarshal(data, &manifest); err != nil {
+		return err
+	}
+	if len(manifest.Files) == 0 {
+		p.Breadcrumb(ctx, "java runtime has no recorded file hashes")
+		return fmt.Errorf("%w: no file hashes recorded", errNeedsInstall)
+	}
+
+	actual, err := hashTree(p.alpinePath("jre", "17", "extracted"))
+	if err != nil {
+		return err
+	}
+
+	var broken int
+	for path, hash := range manifest.Files {
+		if actual[path] != hash {
+			broken++
+		}
+	}
+	if broken > 0 {
+		return fmt.Errorf("%d file(s) missing or corrupted", broken)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alpine-client/pinnacle/ui"
+)
+
+func TestVerifyJavaFiles(t *testing.T) {
+	hash, err := hashReader(strings.NewReader("java"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		files     map[string]string
+		content   string
+		wantErr   string
+		reinstall bool
+	}{
+		{name: "intact", files: map[string]string{"bin/java": hash}, content: "java"},
+		{name: "corrupted", files: map[string]string{"bin/java": hash}, content: "jav", wantErr: "1 file(s) missing or corrupted"},
+		{
+			name:    "missing",
+			files:   map[string]string{"bin/java": hash, "lib/modules": hash},
+			content: "java",
+			wantErr: "1 file(s) missing or corrupted",
+		},
+		{name: "no recorded hashes", content: "jav", wantErr: "no file hashes recorded", reinstall: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := testPinnacle(t.TempDir())
+
+			bin := p.alpinePath("jre", "17", "extracted", "bin")
+			if err := os.MkdirAll(bin, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(bin, "java"), []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			data, err := json.Marshal(JavaManifest{Hash: "archive", Files: tt.files})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = os.WriteFile(p.alpinePath("jre", "17", "version.json"), data, 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			err = p.verifyJavaFiles(context.Background())
+			checkErrContains(t, err, tt.wantErr)
+			if errors.Is(err, errNeedsInstall) != tt.reinstall {
+				t.Errorf("verifyJavaFiles() error = %v, want needs install %t", err, tt.reinstall)
+			}
+		})
+	}
+}
+
+func TestVerifyJava(t *testing.T) {
+	jre := &MetadataResponse{Name: "jre.zip", Hash: strings.Repeat("a", 40)}
+
+	tests := []struct {
+		name     string
+		java     bool
+		manifest string // contents of version.json, none if empty
+		wantErr  error
+	}{
+		{name: "installed", java: true, manifest: `{"checksum":"` + jre.Hash + `"}`},
+		{name: "missing executable", manifest: `{"checksum":"` + jre.Hash + `"}`, wantErr: errMissingJava},
+		{name: "missing manifest", java: true, wantErr: errMissingJava},
+		{name: "invalid manifest", java: true, manifest: "{", wantErr: errMissingJava},
+		{name: "other archive", java: true, manifest: `{"checksum":"` + strings.Repeat("b", 40) + `"}`, wantErr: errMissingJava},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := testPinnacle(t.TempDir())
+			p.os = Linux
+
+			bin := p.alpinePath("jre", "17", "extracted", "bin")
+			if err := os.MkdirAll(bin, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if tt.java {
+				if err := os.WriteFile(filepath.Join(bin, "java"), nil, 0o700); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.manifest != "" {
+				if err := os.WriteFile(p.alpinePath("jre", "17", "version.json"), []byte(tt.manifest), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := p.verifyJava(context.Background(), jre, ui.NewProgressTask("test"))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("verifyJava() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil && !errors.Is(err, errNeedsInstall) {
+				t.Errorf("verifyJava() error = %v doesn't ask for an install", err)
+			}
+		})
+	}
+}
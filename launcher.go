@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+)
+
+// launcherComponent is the Alpine Client launcher jar.
+type launcherComponent struct {
+	p *Pinnacle
+}
+
+func (*launcherComponent) Name() string {
+	return "launcher"
+}
+
+func (*launcherComponent) Requires() []string {
+	return nil
+}
+
+func (c *launcherComponent) Check(ctx context.Context) (*MetadataResponse, error) {
+	return c.p.checkLauncher(ctx)
+}
+
+func (c *launcherComponent) Install(ctx context.Context, meta *MetadataResponse) error {
+	return c.p.downloadLauncher(ctx, meta)
+}
+
+func (c *launcherComponent) Verify(ctx context.Context, meta *MetadataResponse) error {
+	_, err := c.p.fileHashMatches(ctx, meta.Hash, c.p.launcherPath())
+	return err
+}
+
+func (c *launcherComponent) Paths() []string {
+	return []string{c.p.launcherPath()}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// lockfileVersion is the lockfile format written by this version.
+const lockfileVersion = 1
+
+// Lockfile records the exact artifacts resolved for every component,
+// so several machines can install the same launcher and runtime.
+type Lockfile struct {
+	Version    int               `json:"version"`
+	Branch     string            `json:"branch"`
+	OS         OperatingSystem   `json:"os"`
+	Arch       Architecture      `json:"arch"`
+	Created    time.Time         `json:"created"`
+	Components []lockedComponent `json:"components"`
+}
+
+type lockedComponent struct {
+	Name     string            `json:"name"`
+	Target   string            `json:"target,omitempty"` // metadata-described components only
+	Requires []string          `json:"requires,omitempty"`
+	Metadata *MetadataResponse `json:"metadata"`
+}
+
+// Lock resolves the current metadata for every component and writes it
+// to path, or to w if path is "-".
+func (p *Pinnacle) Lock(ctx context.Context, w io.Writer, path string) error {
+	if err := p.checkBranch(ctx); err != nil {
+		return err
+	}
+	if err := p.registerManifestComponents(ctx); err != nil {
+		return err
+	}
+	components, err := orderComponents(p.components)
+	if err != nil {
+		return err
+	}
+
+	lock := Lockfile{
+		Version: lockfileVersion,
+		Branch:  p.branch,
+		OS:      p.os,
+		Arch:    p.arch,
+		Created: time.Now().UTC(),
+	}
+	for _, c := range components {
+		meta, err := c.Check(ctx)
+		if err != nil && !errors.Is(err, errNeedsInstall) {
+			return fmt.Errorf("%s: %w", c.Name(), err)
+		}
+		if meta == nil {
+			return fmt.Errorf("%s: no metadata", c.Name())
+		}
+
+		entry := lockedComponent{Name: c.Name(), Metadata: meta}
+		if mc, ok := c.(*manifestComponent); ok {
+			entry.Target = mc.desc.Target
+			entry.Requires = mc.desc.Requires
+		}
+		lock.Components = append(lock.Components, entry)
+	}
+
+	data, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if path == "-" {
+		_, err = w.Write(data)
+		return err
+	}
+	if err = os.WriteFile(path, data, 0o644); err != nil { //nolint:gosec // lockfiles are meant to be shared
+		return err
+	}
+	_, _ = fmt.Fprintf(w, "Locked %d component(s) on branch %s to %s\n", len(lock.Components), lock.Branch, path)
+	return nil
+}
+
+// loadLock reads a lockfile and makes every component install exactly
+// the artifacts it records instead of asking the metadata server.
+func (p *Pinnacle) loadLock(path string) error {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return err
+	}
+
+	var lock Lockfile
+	if err = json.Unmarshal(data, &lock); err != nil {
+		return fmt.Errorf("invalid lockfile %s: %w", path, err)
+	}
+	if lock.Version != lockfileVersion {
+		return fmt.Errorf("lockfile %s has unsupported version %d", path, lock.Version)
+	}
+	if !branchPattern.MatchString(lock.Branch) {
+		return fmt.Errorf("lockfile %s has invalid branch %q", path, lock.Branch)
+	}
+	if lock.OS != p.os || lock.Arch != p.arch {
+		return fmt.Errorf("lockfile %s was made for %s/%s, not %s/%s", path, lock.OS, lock.Arch, p.os, p.arch)
+	}
+
+	locked := make(map[string]*MetadataResponse, len(lock.Components))
+	var descriptors []componentDescriptor
+	for _, c := range lock.Components {
+		if c.Metadata == nil {
+			return fmt.Errorf("lockfile %s: %s has no metadata", path, c.Name)
+		}
+		if err = c.Metadata.validate(); err != nil {
+			return fmt.Errorf("lockfile %s: %s: %w", path, c.Name, err)
+		}
+		locked[c.Name] = c.Metadata
+		if c.Target != "" {
+			descriptors = append(descriptors, componentDescriptor{Name: c.Name, Target: c.Target, Requires: c.Requires})
+		}
+	}
+	// Locked targets are deleted on cleanup and uninstall just like listed ones.
+	if err = validateTargets(descriptors); err != nil {
+		return fmt.Errorf("lockfile %s: %w", path, err)
+	}
+
+	p.lock = locked
+	for _, d := range descriptors {
+		p.register(&manifestComponent{p: p, desc: d})
+	}
+	p.manifestsRegistered = true // only the locked components are installed
+
+	p.branch = lock.Branch
+	return p.loadToken()
+}
+
+// componentMetadata returns the metadata for a component, from the
+// lockfile if one is in use and otherwise from the metadata server.
+func (p *Pinnacle) componentMetadata(ctx context.Context, name string, url string, header http.Header) (*MetadataResponse, error) {
+	if p.lock == nil {
+		return p.fetchMetadata(ctx, url, header)
+	}
+	meta, found := p.lock[name]
+	if !found {
+		return nil, fmt.Errorf("the lockfile has no entry for %s", name)
+	}
+	p.Breadcrumb(ctx, "using locked metadata for "+name)
+	return meta, nil
+}
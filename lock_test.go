@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadLock(t *testing.T) {
+	artifact := func() *MetadataResponse {
+		return &MetadataResponse{URL: "https://cdn.example.com/a", Hash: strings.Repeat("a", 40), Size: 1}
+	}
+	files := func() *MetadataResponse {
+		return &MetadataResponse{Files: []ManifestFile{}}
+	}
+
+	tests := []struct {
+		name       string
+		edit       func(l *Lockfile)
+		wantErr    string
+		wantLocked []string
+		wantTarget string
+	}{
+		{name: "valid", wantLocked: []string{"java", "launcher", "mods"}, wantTarget: "mods"},
+		{name: "wrong version", edit: func(l *Lockfile) { l.Version = 2 }, wantErr: "unsupported version 2"},
+		{name: "wrong os", edit: func(l *Lockfile) { l.OS = Windows }, wantErr: "was made for windows/amd64"},
+		{name: "wrong arch", edit: func(l *Lockfile) { l.Arch = Arm64 }, wantErr: "was made for linux/arm64"},
+		{name: "invalid branch", edit: func(l *Lockfile) { l.Branch = "../beta" }, wantErr: `invalid branch "../beta"`},
+		{name: "missing metadata", edit: func(l *Lockfile) { l.Components[0].Metadata = nil }, wantErr: "java has no metadata"},
+		{
+			name:    "invalid metadata",
+			edit:    func(l *Lockfile) { l.Components[1].Metadata.Hash = "nope" },
+			wantErr: "launcher: invalid sha1",
+		},
+		{
+			name:    "target outside data directory",
+			edit:    func(l *Lockfile) { l.Components[2].Target = "../mods" },
+			wantErr: "must be a directory inside the data directory",
+		},
+		{
+			name:    "whole data directory",
+			edit:    func(l *Lockfile) { l.Components[2].Target = "." },
+			wantErr: "must be a directory inside the data directory",
+		},
+		{
+			name:    "reserved target",
+			edit:    func(l *Lockfile) { l.Components[2].Target = "jre" },
+			wantErr: `target "jre" is reserved for Pinnacle`,
+		},
+		{
+			name:    "launcher target",
+			edit:    func(l *Lockfile) { l.Components[2].Target = "launcher.jar" },
+			wantErr: "is reserved for Pinnacle",
+		},
+		{
+			name: "overlapping targets",
+			edit: func(l *Lockfile) {
+				l.Components = append(l.Components, lockedComponent{Name: "shaders", Target: "mods/shaders", Metadata: files()})
+			},
+			wantErr: `overlaps component "mods"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PINNACLE_TOKEN", "")
+			dir := t.TempDir()
+
+			lock := Lockfile{
+				Version: lockfileVersion,
+				Branch:  "beta",
+				OS:      Linux,
+				Arch:    x86,
+				Components: []lockedComponent{
+					{Name: "java", Metadata: artifact()},
+					{Name: "launcher", Metadata: artifact()},
+					{Name: "mods", Target: "mods", Requires: []string{"launcher"}, Metadata: files()},
+				},
+			}
+			if tt.edit != nil {
+				tt.edit(&lock)
+			}
+			data, err := json.Marshal(lock)
+			if err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(dir, "pinnacle.lock")
+			if err = os.WriteFile(path, data, 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			p := testPinnacle(dir)
+			p.os, p.arch = Linux, x86
+			err = p.loadLock(path)
+			checkErrContains(t, err, tt.wantErr)
+			if err != nil {
+				if len(p.components) > 0 {
+					t.Errorf("registered %d component(s) from a rejected lockfile", len(p.components))
+				}
+				return
+			}
+
+			if p.branch != "beta" {
+				t.Errorf("branch = %q, want beta", p.branch)
+			}
+			for _, name := range tt.wantLocked {
+				if p.lock[name] == nil {
+					t.Errorf("no locked metadata for %s", name)
+				}
+			}
+			if len(p.components) != 1 || p.components[0].(*manifestComponent).desc.Target != tt.wantTarget {
+				t.Errorf("registered %v, want only the %s component", p.components, tt.wantTarget)
+			}
+		})
+	}
+}
+
+func TestComponentMetadataLocked(t *testing.T) {
+	locked := &MetadataResponse{URL: "https://cdn.example.com/launcher.jar"}
+	p := testPinnacle(t.TempDir())
+	p.lock = map[string]*MetadataResponse{"launcher": locked}
+
+	// The URL is unreachable, so any request would fail.
+	got, err := p.componentMetadata(context.Background(), "launcher", "http://127.0.0.1:0/pinnacle", nil)
+	if err != nil || got != locked {
+		t.Errorf("componentMetadata(launcher) = %v, %v, want the locked entry", got, err)
+	}
+
+	_, err = p.componentMetadata(context.Background(), "java", "http://127.0.0.1:0/jre", nil)
+	checkErrContains(t, err, "the lockfile has no entry for java")
+}
@@ -2,6 +2,10 @@
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
 	"runtime"
 	"time"
 
@@ -18,15 +22,27 @@ func main() {
 		version: version,
 	}
 
-	if err := p.setup(); err != nil {
-		panic(err)
+	cmd, err := p.setup(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "pinnacle:", err)
+		os.Exit(2)
 	}
 	defer sentry.Flush(2 * time.Second)
 
-	p.Run()
+	if err = cmd.run(context.Background(), p); err != nil {
+		p.logger.Error(err.Error())
+		sentry.Flush(2 * time.Second)
+		os.Exit(1)
+	}
 }
 
-func (p *Pinnacle) Run() {
+// Run brings every component up to date and starts the launcher. A
+// failure is shown in a dialog and then returned, so the exit status
+// tells scripts whether the launcher started.
+func (p *Pinnacle) Run() error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -37,43 +53,52 @@ func (p *Pinnacle) Run() {
 	done := make(chan bool)
 	defer close(done)
 
-	type task struct {
-		c context.Context
-		j func(context.Context) error
-		f func(context.Context, error) error
-	}
-
+	var err error
 	go func() {
-		for _, t := range []task{
-			{
-				c: p.client.NewContext(ctx, "java"),
-				j: p.checkJava,
-				f: p.download,
-			},
-			{
-				c: p.client.NewContext(ctx, "launcher"),
-				j: p.checkLauncher,
-				f: p.download,
-			},
-			{
-				c: p.client.NewContext(ctx, "start"),
-				j: p.startLauncher,
-				f: p.error,
-			},
-		} {
-			err := t.f(t.c, t.j(t.c))
-			if err != nil {
-				p.cleanup(t.c, err)
-				break
-			}
-		}
+		defer func() {
+			ui.Close()
+			done <- true
+		}()
 
-		ui.Close()
-		done <- true
+		var fctx context.Context
+		if fctx, err = p.launch(ctx); err != nil {
+			p.cleanup(fctx, err)
+		}
 	}()
 
 	<-done
 	if p.logFile != nil {
 		p.CaptureErr(ctx, p.logFile.Close())
 	}
+	return err
+}
+
+// launch installs or updates every component and starts the launcher.
+// On failure it also returns the context to report the error with.
+func (p *Pinnacle) launch(ctx context.Context) (context.Context, error) {
+	if p.lock == nil {
+		if err := p.checkBranch(ctx); err != nil {
+			return ctx, err
+		}
+		if p.isFlagSet("branch") || p.isFlagSet("launcher-version") {
+			p.CaptureErr(ctx, p.rememberSelection())
+		}
+		if p.config.LauncherVersion != "" {
+			ui.NotifyUpdatesPaused(p.logger, p.config.LauncherVersion)
+		}
+	}
+
+	p.registerOptionalComponents(ctx)
+
+	if cctx, err := p.ensureAll(ctx); err != nil {
+		return cctx, err
+	}
+	p.CaptureErr(ctx, p.recordState(func(s *runState) { s.LastUpdate = time.Now() }))
+
+	sctx := p.client.NewContext(ctx, "start")
+	if err := p.startLauncher(sctx); err != nil {
+		return sctx, err
+	}
+	p.CaptureErr(ctx, p.recordState(func(s *runState) { s.LastLaunch = time.Now() }))
+	return ctx, nil
 }
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/alpine-client/pinnacle/ui"
+)
+
+// installedManifestName is the file recording what a manifest component
+// installed, so files dropped from the manifest can be deleted safely.
+const installedManifestName = ".pinnacle-manifest.json"
+
+// ManifestFile is a single file of a multi-file component.
+type ManifestFile struct {
+	Path       string `json:"path"` // slash-separated, relative to the component target
+	URL        string `json:"url"`
+	Hash       string `json:"sha1"`
+	Size       uint32 `json:"size"`
+	Executable bool   `json:"executable,omitempty"`
+}
+
+// componentDescriptor is an entry of the /components metadata listing.
+type componentDescriptor struct {
+	Name     string   `json:"name"`
+	Target   string   `json:"target"` // slash-separated, relative to the data directory
+	Requires []string `json:"requires,omitempty"`
+}
+
+// manifestComponent is a component described entirely by metadata as a
+// list of files, which are synced into a target directory.
+type manifestComponent struct {
+	p    *Pinnacle
+	desc componentDescriptor
+}
+
+// registerManifestComponents fetches the components listed by the
+// metadata server for the current branch and registers them. A server
+// without the listing is treated as having none.
+func (p *Pinnacle) registerManifestComponents(ctx context.Context) error {
+	if p.manifestsRegistered {
+		return nil
+	}
+	endpoint := MetadataURL + "/components?branch=" + url.QueryEscape(p.branch)
+
+	resp, err := p.request(ctx, endpoint, p.authHeader(endpoint), http.StatusOK)
+	if errors.Is(err, errNotFound) {
+		p.manifestsRegistered = true
+		return nil
+	}
+	if err != nil {
+		return p.branchAccess(err)
+	}
+	defer func() {
+		p.CaptureErr(ctx, resp.Body.Close())
+	}()
+
+	if err = checkCaptivePortal(endpoint, resp, true); err != nil {
+		return err
+	}
+
+	var descriptors []componentDescriptor
+	if err = json.NewDecoder(resp.Body).Decode(&descriptors); err != nil {
+		return err
+	}
+
+	if err = validateTargets(descriptors); err != nil {
+		return err
+	}
+	for _, d := range descriptors {
+		p.register(&manifestComponent{p: p, desc: d})
+	}
+	p.manifestsRegistered = true
+	return nil
+}
+
+// registerOptionalComponents is registerManifestComponents for the run
+// command. The built-in components are enough to start the launcher, so
+// a failure to fetch the others is noted and they are tried next run.
+// Repair and status keep treating it as an error.
+func (p *Pinnacle) registerOptionalComponents(ctx context.Context) {
+	if err := p.registerManifestComponents(ctx); err != nil {
+		p.Breadcrumb(ctx, "continuing with the built-in components: "+err.Error(), slog.LevelWarn)
+	}
+}
+
+// reservedTargets are the top-level entries of the data directory that
+// belong to Pinnacle or the built-in components.
+var reservedTargets = []string{
+	"jre", "logs", "launcher.jar", "config.json", "credentials.json",
+	"state.json", "state.json.tmp", installedManifestName, migratedMarker,
+}
+
+// validateTargets rejects components whose target would take over the
+// whole data directory, anything Pinnacle owns, or another component's
+// target, since their files are deleted on cleanup and uninstall.
+func validateTargets(descriptors []componentDescriptor) error {
+	targets := make([]string, 0, len(descriptors))
+	for _, d := range descriptors {
+		target := path.Clean(d.Target)
+		if d.Name == "" || target == "." || !filepath.IsLocal(filepath.FromSlash(target)) {
+			return fmt.Errorf("invalid component %q: target %q must be a directory inside the data directory", d.Name, d.Target)
+		}
+
+		top, _, _ := strings.Cut(target, "/")
+		if jar, _ := path.Match("launcher-*.jar", top); jar || slices.Contains(reservedTargets, top) {
+			return fmt.Errorf("invalid component %q: target %q is reserved for Pinnacle", d.Name, d.Target)
+		}
+		for i, other := range targets {
+			if pathsOverlap(target, other) {
+				return fmt.Errorf("invalid component %q: target %q overlaps component %q",
+					d.Name, d.Target, descriptors[i].Name)
+			}
+		}
+		targets = append(targets, target)
+	}
+	return nil
+}
+
+// pathsOverlap reports whether the clean slash-separated paths a and b
+// are the same or one contains the other.
+func pathsOverlap(a string, b string) bool {
+	return a == b || strings.HasPrefix(a, b+"/") || strings.HasPrefix(b, a+"/")
+}
+
+func (c *manifestComponent) Name() string {
+	return c.desc.Name
+}
+
+func (c *manifestComponent) Requires() []string {
+	return c.desc.Requires
+}
+
+func (c *manifestComponent) Check(ctx context.Context) (*MetadataResponse, error) {
+	p := c.p
+	endpoint := fmt.Sprintf("%s/component?name=%s&branch=%s",
+		MetadataURL, url.QueryEscape(c.desc.Name), url.QueryEscape(p.branch))
+
+	meta, err := p.componentMetadata(ctx, c.desc.Name, endpoint, p.authHeader(endpoint))
+	if err != nil {
+		return nil, p.branchAccess(err)
+	}
+	if meta.Files == nil {
+		// Syncing an empty list would delete everything installed.
+		return nil, fmt.Errorf("component %s: metadata has no file list", c.desc.Name)
+	}
+
+	downloads, removes := c.pending(meta)
+	if len(downloads) > 0 || len(removes) > 0 {
+		return meta, fmt.Errorf("%w: %d file(s) to download, %d to remove",
+			errNeedsInstall, len(downloads), len(removes))
+	}
+
+	p.Breadcrumb(ctx, "finished check of "+c.desc.Name+" (up to date)")
+	return meta, nil
+}
+
+// Install brings the target directory in line with meta: new and changed
+// files are downloaded, and files installed previously but no longer
+// listed are deleted. Files Pinnacle didn't install are never overwritten
+// or deleted: one that already matches its listing is taken over as is,
+// and any other one fails the install.
+func (c *manifestComponent) Install(ctx context.Context, meta *MetadataResponse) error {
+	p := c.p
+	pt := ui.NewProgressTask("Downloading " + c.desc.Name + "...")
+	defer pt.Done()
+
+	var total, done int64
+	for _, f := range meta.Files {
+		total += int64(f.Size)
+	}
+
+	installed := c.readInstalled()
+	for _, f := range meta.Files {
+		dest := c.path(f.Path)
+		if match, _ := p.fileHashMatches(ctx, f.Hash, dest); !match {
+			if _, tracked := installed[f.Path]; !tracked && fileExists(dest) {
+				return fmt.Errorf("component %s: %s already exists and wasn't installed by Pinnacle, "+
+					"move it elsewhere and try again", c.desc.Name, dest)
+			}
+			if err := c.installFile(ctx, f); err != nil {
+				return err
+			}
+		}
+		installed[f.Path] = f.Hash
+
+		done += int64(f.Size)
+		if total > 0 {
+			pt.UpdateProgress(float64(done) / float64(total))
+		}
+	}
+
+	for _, path := range c.stale(installed, meta) {
+		p.Breadcrumb(ctx, "removing "+path+" from "+c.desc.Name)
+		if err := os.Remove(c.path(path)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		delete(installed, path)
+	}
+
+	return c.writeInstalled(installed)
+}
+
+func (c *manifestComponent) Verify(ctx context.Context, meta *MetadataResponse) error {
+	for _, f := range meta.Files {
+		if _, err := c.p.fileHashMatches(ctx, f.Hash, c.path(f.Path)); err != nil {
+			return fmt.Errorf("%s: %w", f.Path, err)
+		}
+	}
+	return nil
+}
+
+// Paths lists only the files recorded as installed, and the record
+// itself, so files Pinnacle never installed in the target survive.
+func (c *manifestComponent) Paths() []string {
+	var paths []string
+	for rel := range c.readInstalled() {
+		if filepath.IsLocal(filepath.FromSlash(rel)) {
+			paths = append(paths, c.path(rel))
+		}
+	}
+	slices.Sort(paths)
+	return append(paths, c.path(installedManifestName))
+}
+
+// pending lists the files that are missing or differ from the installed
+// record, and the previously installed paths meta no longer lists.
+func (c *manifestComponent) pending(meta *MetadataResponse) ([]ManifestFile, []string) {
+	var downloads []ManifestFile
+
+	installed := c.readInstalled()
+	for _, f := range meta.Files {
+		info, err := os.Stat(c.path(f.Path))
+		if err != nil || installed[f.Path] != f.Hash || info.Size() != int64(f.Size) {
+			downloads = append(downloads, f)
+		}
+	}
+	return downloads, c.stale(installed, meta)
+}
+
+// installFile downloads f next to its destination and moves it into place
+// once the hash matches, so an interrupted sync never leaves a partial file.
+func (c *manifestComponent) installFile(ctx context.Context, f ManifestFile) error {
+	p := c.p
+	dest := c.path(f.Path)
+	partial := dest + ".part"
+
+	if err := os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
+		return err
+	}
+
+	if err := p.downloadFile(ctx, f.URL, p.authHeader(f.URL), partial, f.Size, nil); err != nil {
+		return err
+	}
+	if _, err := p.fileHashMatches(ctx, f.Hash, partial); err != nil {
+		_ = os.Remove(partial)
+		return fmt.Errorf("%s: %w", f.Path, err)
+	}
+
+	mode := os.FileMode(0o644)
+	if f.Executable {
+		mode = 0o755
+	}
+	if err := os.Chmod(partial, mode); err != nil {
+		return err
+	}
+	return os.Rename(partial, dest)
+}
+
+// stale returns previously installed paths that meta no longer lists.
+func (*manifestComponent) stale(installed map[string]string, meta *MetadataResponse) []string {
+	var paths []string
+	for path := range installed {
+		if !filepath.IsLocal(filepath.FromSlash(path)) {
+			continue // never delete outside the target
+		}
+		if !slices.ContainsFunc(meta.Files, func(f ManifestFile) bool { return f.Path == path }) {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+func (c *manifestComponent) path(rel string) string {
+	return c.p.alpinePath(filepath.FromSlash(c.desc.Target), filepath.FromSlash(rel))
+}
+
+// readInstalled returns the recorded path to hash mapping of installed files.
+func (c *manifestComponent) readInstalled() map[string]string {
+	installed := make(map[string]string)
+	data, err := os.ReadFile(c.path(installedManifestName))
+	if err == nil {
+		_ = json.Unmarshal(data, &installed)
+	}
+	return installed
+}
+
+func (c *manifestComponent) writeInstalled(installed map[string]string) error {
+	data, err := json.Marshal(installed)
+	if err != nil {
+		return err
+	}
+
+	path := c.path(installedManifestName)
+	if err = os.WriteFile(path+".tmp", data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// emptySHA1 is the sha1 of an empty file.
+const emptySHA1 = "da39a3ee5e6b4b0d3255bfef95601890afd80709"
+
+func TestValidateTargets(t *testing.T) {
+	tests := []struct {
+		name    string
+		targets []string
+		wantErr string
+	}{
+		{name: "separate targets", targets: []string{"mods", "resourcepacks/alpine", "shaders"}},
+		{name: "nested target", targets: []string{"resourcepacks/alpine"}},
+		{name: "empty", targets: []string{""}, wantErr: "must be a directory inside"},
+		{name: "data directory", targets: []string{"."}, wantErr: "must be a directory inside"},
+		{name: "cleans to data directory", targets: []string{"mods/.."}, wantErr: "must be a directory inside"},
+		{name: "parent", targets: []string{"../mods"}, wantErr: "must be a directory inside"},
+		{name: "absolute", targets: []string{"/tmp/mods"}, wantErr: "must be a directory inside"},
+		{name: "java runtime", targets: []string{"jre"}, wantErr: "reserved for Pinnacle"},
+		{name: "inside java runtime", targets: []string{"jre/17/extracted"}, wantErr: "reserved for Pinnacle"},
+		{name: "logs", targets: []string{"logs"}, wantErr: "reserved for Pinnacle"},
+		{name: "config", targets: []string{"config.json"}, wantErr: "reserved for Pinnacle"},
+		{name: "credentials", targets: []string{"credentials.json"}, wantErr: "reserved for Pinnacle"},
+		{name: "launcher", targets: []string{"launcher.jar"}, wantErr: "reserved for Pinnacle"},
+		{name: "branch launcher", targets: []string{"launcher-beta.jar"}, wantErr: "reserved for Pinnacle"},
+		{name: "same target", targets: []string{"mods", "mods/"}, wantErr: "overlaps component"},
+		{name: "target inside another", targets: []string{"mods", "mods/alpine"}, wantErr: "overlaps component"},
+		{name: "target around another", targets: []string{"mods/alpine", "mods"}, wantErr: "overlaps component"},
+		{name: "shared prefix", targets: []string{"mods", "mods-extra"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			descriptors := make([]componentDescriptor, len(tt.targets))
+			for i, target := range tt.targets {
+				descriptors[i] = componentDescriptor{Name: "component" + string(rune('a'+i)), Target: target}
+			}
+			checkErrContains(t, validateTargets(descriptors), tt.wantErr)
+		})
+	}
+}
+
+func TestManifestComponentStale(t *testing.T) {
+	meta := &MetadataResponse{Files: []ManifestFile{{Path: "a.jar"}, {Path: "sub/b.jar"}}}
+
+	tests := []struct {
+		name      string
+		installed map[string]string
+		want      []string
+	}{
+		{name: "nothing installed", installed: map[string]string{}},
+		{name: "all listed", installed: map[string]string{"a.jar": "1", "sub/b.jar": "2"}},
+		{name: "dropped file", installed: map[string]string{"a.jar": "1", "old.jar": "3"}, want: []string{"old.jar"}},
+		{name: "dropped nested file", installed: map[string]string{"sub/old.jar": "3"}, want: []string{"sub/old.jar"}},
+		{
+			name:      "outside the target",
+			installed: map[string]string{"../config.json": "1", "/etc/passwd": "2", "sub/../../x": "3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := (&manifestComponent{}).stale(tt.installed, meta)
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("stale() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManifestComponentPaths(t *testing.T) {
+	dir := t.TempDir()
+	c := &manifestComponent{
+		p:    &Pinnacle{dataDir: dir},
+		desc: componentDescriptor{Name: "mods", Target: "mods"},
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "mods"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest := filepath.Join(dir, "mods", installedManifestName)
+	if got := c.Paths(); !slices.Equal(got, []string{manifest}) {
+		t.Errorf("Paths() without installed files = %q, want only the manifest", got)
+	}
+
+	installed := map[string]string{"a.jar": "1", "sub/b.jar": "2", "../escape.jar": "3"}
+	if err := c.writeInstalled(installed); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		filepath.Join(dir, "mods", "a.jar"),
+		filepath.Join(dir, "mods", "sub", "b.jar"),
+		manifest,
+	}
+	if got := c.Paths(); !slices.Equal(got, want) {
+		t.Errorf("Paths() = %q, want %q", got, want)
+	}
+}
+
+func TestManifestComponentInstallUntracked(t *testing.T) {
+	hash, err := hashReader(strings.NewReader("mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Nothing is served at the URL, so any download fails.
+	meta := &MetadataResponse{Files: []ManifestFile{{Path: "a.jar", URL: "http://127.0.0.1:0/a.jar", Hash: hash, Size: 3}}}
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "matching file is taken over", content: "mod"},
+		{name: "different file is kept", content: "user's own", wantErr: "wasn't installed by Pinnacle"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			c := &manifestComponent{p: testPinnacle(dir), desc: componentDescriptor{Name: "mods", Target: "mods"}}
+			dest := filepath.Join(dir, "mods", "a.jar")
+			if err := os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(dest, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			err := c.Install(context.Background(), meta)
+			checkErrContains(t, err, tt.wantErr)
+
+			if data, _ := os.ReadFile(dest); string(data) != tt.content {
+				t.Errorf("a.jar = %q, want it untouched (%q)", data, tt.content)
+			}
+			_, tracked := c.readInstalled()["a.jar"]
+			if tracked != (err == nil) {
+				t.Errorf("a.jar recorded as installed = %t, want %t", tracked, err == nil)
+			}
+		})
+	}
+}
+
+func TestManifestComponentInstallEmpty(t *testing.T) {
+	dir := t.TempDir()
+	c := &manifestComponent{p: testPinnacle(dir), desc: componentDescriptor{Name: "mods", Target: "mods"}}
+	if err := os.MkdirAll(filepath.Join(dir, "mods"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.writeInstalled(map[string]string{"old.jar": "1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	meta := &MetadataResponse{Files: []ManifestFile{{Path: "empty.txt", URL: "http://127.0.0.1:0/empty.txt", Hash: emptySHA1}}}
+	if err := os.WriteFile(filepath.Join(dir, "mods", "empty.txt"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Install(context.Background(), meta); err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+	if got := c.readInstalled(); len(got) != 1 || got["empty.txt"] != emptySHA1 {
+		t.Errorf("installed = %v, want only empty.txt", got)
+	}
+}
+
+func TestRegisterOptionalComponents(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{name: "server error", status: http.StatusBadGateway},
+		{name: "invalid json", status: http.StatusOK, body: "{"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			t.Cleanup(srv.Close)
+			serveAs(t, srv)
+
+			p := testPinnacle(t.TempDir())
+			p.branch = "production"
+			p.config.Retries = new(int)
+			p.register(&fakeComponent{name: "launcher"})
+
+			p.registerOptionalComponents(context.Background())
+			if len(p.components) != 1 || p.manifestsRegistered {
+				t.Errorf("run path: %d components, registered %v, want only the built-in one",
+					len(p.components), p.manifestsRegistered)
+			}
+
+			if err := p.registerManifestComponents(context.Background()); err == nil {
+				t.Error("registerManifestComponents() error = nil, want the failure for repair and status")
+			}
+		})
+	}
+}
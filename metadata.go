@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// metadataSchemaVersion is the newest metadata schema this build
+// understands. Responses without a schema_version are treated as 1.
+const metadataSchemaVersion = 1
+
+// updateRequiredError is returned when metadata requires a newer
+// Pinnacle than the one running.
+type updateRequiredError struct {
+	required string
+}
+
+func (e *updateRequiredError) Error() string {
+	if e.required == "" {
+		return "metadata requires a newer version of Pinnacle"
+	}
+	return fmt.Sprintf("metadata requires Pinnacle %s or newer (running %s)", e.required, version)
+}
+
+// validate checks every field of the response and returns
+// a descriptive error for the first invalid one.
+func (m *MetadataResponse) validate() error {
+	if m.SchemaVersion > metadataSchemaVersion {
+		return &updateRequiredError{required: m.MinPinnacleVersion}
+	}
+	if m.MinPinnacleVersion != "" && version != "" {
+		older, err := versionLess(version, m.MinPinnacleVersion)
+		if err != nil {
+			return fmt.Errorf("invalid min_pinnacle_version: %w", err)
+		}
+		if older {
+			return &updateRequiredError{required: m.MinPinnacleVersion}
+		}
+	}
+
+	// name is only sent for archives, where it becomes a file name on disk.
+	if m.Name != "" && (m.Name != filepath.Base(m.Name) || m.Name == "..") {
+		return fmt.Errorf("invalid name %q: must be a plain file name", m.Name)
+	}
+
+	// A response describes either a single download or a list of files,
+	// never both. An empty files list is a component with nothing in it.
+	switch {
+	case m.Files != nil && m.URL != "":
+		return errors.New("invalid response: has both url and files")
+	case m.Files == nil:
+		if err := validateArtifact(m.URL, m.Hash); err != nil {
+			return err
+		}
+		if m.Size == 0 {
+			return errors.New("invalid size: must be greater than zero")
+		}
+		return nil
+	}
+
+	seen := make(map[string]bool, len(m.Files))
+	for _, f := range m.Files {
+		if !filepath.IsLocal(filepath.FromSlash(f.Path)) {
+			return fmt.Errorf("invalid file path %q: must be relative to the component", f.Path)
+		}
+		if seen[f.Path] {
+			return fmt.Errorf("duplicate file path %q", f.Path)
+		}
+		seen[f.Path] = true
+
+		if err := validateArtifact(f.URL, f.Hash); err != nil {
+			return fmt.Errorf("file %s: %w", f.Path, err)
+		}
+	}
+	return nil
+}
+
+// validateArtifact checks the url and hash describing a single download.
+func validateArtifact(rawURL string, hash string) error {
+	u, err := url.Parse(rawURL)
+	switch {
+	case rawURL == "":
+		return errors.New("missing url")
+	case err != nil:
+		return fmt.Errorf("invalid url: %w", err)
+	case u.Scheme != "https" && u.Scheme != "http":
+		return fmt.Errorf("invalid url %q: unsupported scheme", rawURL)
+	case u.Host == "":
+		return fmt.Errorf("invalid url %q: missing host", rawURL)
+	}
+
+	if _, err = hex.DecodeString(hash); err != nil || len(hash) != 40 {
+		return fmt.Errorf("invalid sha1 %q: expected 40 hex characters", hash)
+	}
+	return nil
+}
+
+// versionLess reports whether semantic version a is older than b.
+// A leading "v" and any pre-release or build suffix are ignored.
+func versionLess(a string, b string) (bool, error) {
+	va, err := parseVersion(a)
+	if err != nil {
+		return false, err
+	}
+	vb, err := parseVersion(b)
+	if err != nil {
+		return false, err
+	}
+	for i := range va {
+		if va[i] != vb[i] {
+			return va[i] < vb[i], nil
+		}
+	}
+	return false, nil
+}
+
+func parseVersion(v string) ([3]int, error) {
+	var out [3]int
+
+	core, _, _ := strings.Cut(strings.TrimPrefix(v, "v"), "-")
+	core, _, _ = strings.Cut(core, "+")
+
+	parts := strings.Split(core, ".")
+	if len(parts) > len(out) {
+		return out, fmt.Errorf("invalid version %q", v)
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return out, fmt.Errorf("invalid version %q", v)
+		}
+		out[i] = n
+	}
+	return out, nil
+}
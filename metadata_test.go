@@ -0,0 +1,222 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    [3]int
+		wantErr bool
+	}{
+		{input: "1.2.3", want: [3]int{1, 2, 3}},
+		{input: "v1.2.3", want: [3]int{1, 2, 3}},
+		{input: "1.2", want: [3]int{1, 2, 0}},
+		{input: "2", want: [3]int{2, 0, 0}},
+		{input: "1.2.3-beta.1", want: [3]int{1, 2, 3}},
+		{input: "1.2.3+build.5", want: [3]int{1, 2, 3}},
+		{input: "", wantErr: true},
+		{input: "1.2.3.4", wantErr: true},
+		{input: "1.x.3", wantErr: true},
+		{input: "1.-2.3", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseVersion(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseVersion(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("parseVersion(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVersionLess(t *testing.T) {
+	tests := []struct {
+		a, b    string
+		want    bool
+		wantErr bool
+	}{
+		{a: "1.2.3", b: "1.2.4", want: true},
+		{a: "1.2.4", b: "1.2.3", want: false},
+		{a: "1.2.3", b: "1.2.3", want: false},
+		{a: "1.9.0", b: "1.10.0", want: true},
+		{a: "v2.0.0", b: "1.99.99", want: false},
+		{a: "1.2", b: "1.2.0", want: false},
+		{a: "1.2.3-rc.1", b: "1.2.3", want: false},
+		{a: "1.2.3", b: "latest", wantErr: true},
+		{a: "dev", b: "1.0.0", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+" < "+tt.b, func(t *testing.T) {
+			got, err := versionLess(tt.a, tt.b)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("versionLess(%q, %q) error = %v, wantErr %v", tt.a, tt.b, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("versionLess(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateArtifact(t *testing.T) {
+	const hash = "da39a3ee5e6b4b0d3255bfef95601890afd80709"
+
+	tests := []struct {
+		name    string
+		url     string
+		hash    string
+		wantErr string
+	}{
+		{name: "https", url: "https://cdn.example.com/a.jar", hash: hash},
+		{name: "http", url: "http://cdn.example.com/a.jar", hash: hash},
+		{name: "missing url", url: "", hash: hash, wantErr: "missing url"},
+		{name: "relative url", url: "/a.jar", hash: hash, wantErr: "unsupported scheme"},
+		{name: "file url", url: "file:///etc/passwd", hash: hash, wantErr: "unsupported scheme"},
+		{name: "missing host", url: "https:///a.jar", hash: hash, wantErr: "missing host"},
+		{name: "short hash", url: "https://cdn.example.com/a.jar", hash: hash[:39], wantErr: "invalid sha1"},
+		{name: "non-hex hash", url: "https://cdn.example.com/a.jar", hash: strings.Repeat("z", 40), wantErr: "invalid sha1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateArtifact(tt.url, tt.hash)
+			checkErrContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestMetadataValidate(t *testing.T) {
+	const hash = "da39a3ee5e6b4b0d3255bfef95601890afd80709"
+
+	tests := []struct {
+		name    string
+		version string // running Pinnacle version
+		meta    MetadataResponse
+		wantErr string
+	}{
+		{
+			name: "single download",
+			meta: MetadataResponse{Name: "jre.zip", URL: "https://cdn.example.com/jre.zip", Hash: hash, Size: 10},
+		},
+		{
+			name:    "zero size download",
+			meta:    MetadataResponse{URL: "https://cdn.example.com/jre.zip", Hash: hash},
+			wantErr: "invalid size",
+		},
+		{
+			name:    "missing url",
+			meta:    MetadataResponse{Hash: hash, Size: 10},
+			wantErr: "missing url",
+		},
+		{
+			name:    "name with directory",
+			meta:    MetadataResponse{Name: "../jre.zip", URL: "https://cdn.example.com/jre.zip", Hash: hash, Size: 10},
+			wantErr: "plain file name",
+		},
+		{
+			name:    "newer schema",
+			meta:    MetadataResponse{SchemaVersion: metadataSchemaVersion + 1},
+			wantErr: "newer version of Pinnacle",
+		},
+		{
+			name:    "older pinnacle",
+			version: "1.2.0",
+			meta:    MetadataResponse{MinPinnacleVersion: "1.3.0", URL: "https://cdn.example.com/a.jar", Hash: hash, Size: 1},
+			wantErr: "Pinnacle 1.3.0 or newer",
+		},
+		{
+			name:    "new enough pinnacle",
+			version: "1.3.0",
+			meta:    MetadataResponse{MinPinnacleVersion: "1.3.0", URL: "https://cdn.example.com/a.jar", Hash: hash, Size: 1},
+		},
+		{
+			name: "development build",
+			meta: MetadataResponse{MinPinnacleVersion: "9.0.0", URL: "https://cdn.example.com/a.jar", Hash: hash, Size: 1},
+		},
+		{
+			name: "file list",
+			meta: MetadataResponse{Files: []ManifestFile{
+				{Path: "mods/a.jar", URL: "https://cdn.example.com/a.jar", Hash: hash, Size: 10},
+				{Path: "config/empty.txt", URL: "https://cdn.example.com/empty.txt", Hash: hash},
+			}},
+		},
+		{
+			name: "empty file list",
+			meta: MetadataResponse{Files: []ManifestFile{}},
+		},
+		{
+			name: "both forms",
+			meta: MetadataResponse{
+				URL: "https://cdn.example.com/a.jar", Hash: hash, Size: 10,
+				Files: []ManifestFile{},
+			},
+			wantErr: "both url and files",
+		},
+		{
+			name: "file outside component",
+			meta: MetadataResponse{Files: []ManifestFile{
+				{Path: "../a.jar", URL: "https://cdn.example.com/a.jar", Hash: hash, Size: 10},
+			}},
+			wantErr: "must be relative to the component",
+		},
+		{
+			name: "duplicate file",
+			meta: MetadataResponse{Files: []ManifestFile{
+				{Path: "a.jar", URL: "https://cdn.example.com/a.jar", Hash: hash, Size: 10},
+				{Path: "a.jar", URL: "https://cdn.example.com/b.jar", Hash: hash, Size: 10},
+			}},
+			wantErr: "duplicate file path",
+		},
+		{
+			name: "invalid file",
+			meta: MetadataResponse{Files: []ManifestFile{
+				{Path: "a.jar", URL: "ftp://cdn.example.com/a.jar", Hash: hash, Size: 10},
+			}},
+			wantErr: "file a.jar: invalid url",
+		},
+		{
+			name:    "invalid min version",
+			version: "1.2.0",
+			meta:    MetadataResponse{MinPinnacleVersion: "soon", URL: "https://cdn.example.com/a.jar", Hash: hash, Size: 1},
+			wantErr: "invalid min_pinnacle_version",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setVersion(t, tt.version)
+			err := tt.meta.validate()
+			checkErrContains(t, err, tt.wantErr)
+
+			var updateErr *updateRequiredError
+			if strings.Contains(tt.wantErr, "Pinnacle") && !errors.As(err, &updateErr) {
+				t.Errorf("validate() error = %T, want *updateRequiredError", err)
+			}
+		})
+	}
+}
+
+// setVersion overrides the running Pinnacle version for the rest of the test.
+func setVersion(t *testing.T, v string) {
+	t.Helper()
+	old := version
+	version = v
+	t.Cleanup(func() { version = old })
+}
+
+// checkErrContains fails the test unless err contains want, or is nil
+// when want is empty.
+func checkErrContains(t *testing.T, err error, want string) {
+	t.Helper()
+	switch {
+	case want == "" && err != nil:
+		t.Fatalf("unexpected error: %v", err)
+	case want != "" && (err == nil || !strings.Contains(err.Error(), want)):
+		t.Fatalf("error = %v, want one containing %q", err, want)
+	}
+}
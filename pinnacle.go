@@ -2,75 +2,191 @@
 
 import (
 	"context"
-	"crypto/sha1"
-	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log/slog"
+	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 
 	"github.com/alpine-client/pinnacle/sentry"
 	"github.com/alpine-client/pinnacle/ui"
 )
 
 type Pinnacle struct {
-	logger  *slog.Logger
-	logFile *os.File
-	client  *sentry.Client
-	os      OperatingSystem
-	arch    Architecture
-	version string
-	branch  string
+	logger   *slog.Logger
+	logFile  *os.File
+	client   *sentry.Client
+	os       OperatingSystem
+	arch     Architecture
+	flags    *flag.FlagSet
+	dataDir  string
+	cacheDir string
+	stateDir string
+	// legacyDir is ~/.alpineclient when the XDG directories are used instead.
+	legacyDir string
+	version   string
+	branch    string
+	token     string                       // access token for the branch, if it's private
+	lock      map[string]*MetadataResponse // component name to locked metadata, see loadLock
+	segments  int
+	limiter   *rateLimiter
+	opts      options
+	config    Config
+
+	proxySource string
+
+	components          []Component
+	manifestsRegistered bool
+
+	clockSkew      atomic.Int64 // nanoseconds, local minus server time
+	clockSkewKnown atomic.Bool
 }
 
 type MetadataResponse struct {
-	Name string `json:"name"`
-	URL  string `json:"url"`
-	Hash string `json:"sha1"`
-	Size uint32 `json:"size"`
+	Name               string         `json:"name"`
+	URL                string         `json:"url"`
+	Hash               string         `json:"sha1"`
+	MinPinnacleVersion string         `json:"min_pinnacle_version,omitempty"`
+	Files              []ManifestFile `json:"files"` // nil for single downloads; kept when empty
+	SchemaVersion      int            `json:"schema_version,omitempty"`
+	Size               uint32         `json:"size"`
 }
 
 type JavaManifest struct {
-	Hash string `json:"checksum"`
-	Size uint32 `json:"size"`
+	Files map[string]string `json:"files,omitempty"` // slash-separated path to sha1, relative to extracted
+	Hash  string            `json:"checksum"`
+	Size  uint32            `json:"size"`
 }
 
 var (
-	metadataResponse MetadataResponse
-
-	errMissingJava     = errors.New("missing java")
-	errMissingLauncher = errors.New("missing launcher")
+	errMissingJava     = fmt.Errorf("%w: missing java", errNeedsInstall)
+	errMissingLauncher = fmt.Errorf("%w: missing launcher", errNeedsInstall)
 )
 
-func (p *Pinnacle) setup() error {
-	var err error
+// setup parses args and prepares the command they name. Setup failures
+// of the default command are also shown in a dialog, since it is usually
+// started from a desktop shortcut where nobody sees the terminal.
+func (p *Pinnacle) setup(args []string) (*command, error) {
+	cmd, err := p.parseCommand(args)
+	if err != nil {
+		return nil, err
+	}
+	if err = p.prepare(cmd); err != nil {
+		var cfgErr *configError
+		switch {
+		case cmd.name != defaultCommand:
+		case errors.As(err, &cfgErr):
+			ui.DisplayConfigError(err)
+		default:
+			ui.DisplaySetupError(err)
+		}
+		return nil, err
+	}
+	return cmd, nil
+}
 
-	// Set Launcher Branch
-	branch := flag.String("branch", "production", "Launcher branch")
-	flag.Parse()
-	p.branch = *branch
+// prepare resolves the directories, configuration, logger, network
+// settings, Sentry and components cmd needs.
+func (p *Pinnacle) prepare(cmd *command) error {
+	var err, migrateErr error
+	if !cmd.noData {
+		if err = p.resolveDirs(); err != nil {
+			return err
+		}
+		// Only commands that install or launch move ~/.alpineclient.
+		migrateErr = p.migrateLegacyDir(!cmd.migrate || p.opts.dryRun)
+	}
+	if cmd.network {
+		if p.config, err = p.resolveConfig(); err != nil {
+			return &configError{err: err}
+		}
+		p.branch = p.config.Branch
+		p.segments = p.config.Segments
+		if err = p.loadToken(); err != nil {
+			return err
+		}
+	}
 
 	// Setup Logger
-	err = os.MkdirAll(p.alpinePath("logs"), os.ModePerm) // note: creates .alpineclient AND .alpineclient/logs
+	switch {
+	case cmd.report && !p.opts.dryRun:
+		console := []io.Writer{os.Stderr}
+		if cmd.name == defaultCommand {
+			console = append(console, os.Stdout)
+		}
+		if err = p.openLog(console...); err != nil {
+			return err
+		}
+	default:
+		// stdout is reserved for command output, and nothing may be written to disk
+		p.logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	}
+	slog.SetDefault(p.logger)
+	if migrateErr != nil {
+		p.logger.Warn("migrating data directory: " + migrateErr.Error())
+	}
+
+	if cmd.network {
+		if err = p.configureNetwork(); err != nil {
+			return err
+		}
+	}
+
+	// Setup Sentry
+	if cmd.report {
+		p.StartSentry(version, p.fetchSentryDSN())
+	} else {
+		p.client = sentry.New(slog.New(slog.DiscardHandler))
+	}
+
+	// Register Components
+	p.register(
+		&javaComponent{p: p},
+		&launcherComponent{p: p},
+	)
+
+	return nil
+}
+
+// configureNetwork applies the resolved configuration to the
+// download rate limit, timeouts, TLS and proxy settings. Unusable TLS
+// settings are an error, since ignoring them would weaken or break
+// every connection.
+func (p *Pinnacle) configureNetwork() error {
+	limit, _ := parseByteSize(p.config.RateLimit) // validated by resolveConfig
+	p.limiter = newRateLimiter(limit)
+
+	if err := configureTimeouts(p.config.ConnectTimeout, p.config.ResponseTimeout); err != nil {
+		p.logger.Warn("ignoring timeouts: " + err.Error())
+	}
+	if err := configureTLS(p.config.CAFiles, p.config.TLSMinVersion); err != nil {
+		return fmt.Errorf("TLS settings: %w", err)
+	}
+	if err := p.configureProxy(p.config.Proxy); err != nil {
+		p.logger.Warn("ignoring proxy settings: " + err.Error())
+	}
+	return nil
+}
+
+// openLog creates logs/updater.log and logs to it as well as the console.
+func (p *Pinnacle) openLog(console ...io.Writer) error {
+	err := os.MkdirAll(p.statePath("logs"), os.ModePerm)
 	if err != nil {
 		return err
 	}
 
-	p.logFile, err = os.OpenFile(p.alpinePath("logs", "updater.log"), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o666)
+	p.logFile, err = os.OpenFile(p.statePath("logs", "updater.log"), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o666)
 	if err != nil {
 		return err
 	}
 
-	p.logger = slog.New(slog.NewTextHandler(io.MultiWriter(os.Stdout, os.Stderr, p.logFile), nil))
-	slog.SetDefault(p.logger)
-
-	// Setup Sentry
-	p.StartSentry(version, p.fetchSentryDSN())
-
+	p.logger = slog.New(slog.NewTextHandler(io.MultiWriter(append(console, p.logFile)...), nil))
 	return nil
 }
 
@@ -78,29 +194,54 @@ func (p *Pinnacle) cleanup(ctx context.Context, err error) {
 	if err == nil {
 		return
 	}
-	p.CaptureErr(ctx, ui.DisplayError(ctx, err, p.logFile, p.client))
-	p.CaptureErr(ctx, os.RemoveAll(p.alpinePath("launcher.jar")))
-	p.CaptureErr(ctx, os.RemoveAll(p.alpinePath("jre", "17")))
-}
 
-func (p *Pinnacle) download(ctx context.Context, err error) error {
-	switch {
-	case errors.Is(err, errMissingLauncher):
-		ui.Render(p.logger)
-		return p.downloadLauncher(ctx)
-	case errors.Is(err, errMissingJava):
-		ui.Render(p.logger)
-		return p.downloadJava(ctx)
-	}
-	return err
+	var portalErr *captivePortalError
+	if errors.As(err, &portalErr) {
+		// Not a bug and nothing on disk is broken, so don't report or clean up.
+		p.logger.WarnContext(ctx, err.Error())
+		p.CaptureErr(ctx, ui.DisplayLoginPage(ctx, portalErr.url))
+		return
+	}
+
+	var updateErr *updateRequiredError
+	if errors.As(err, &updateErr) {
+		p.logger.WarnContext(ctx, err.Error())
+		p.CaptureErr(ctx, ui.DisplayUpdateRequired(ctx, updateErr.required))
+		return
+	}
+
+	if needsUserAction(err) {
+		// Repairing or reinstalling can't help, so explain and keep everything.
+		p.logger.WarnContext(ctx, err.Error())
+		p.reportClockSkew(ctx, err)
+		ui.DisplayActionRequired(err)
+		return
+	}
+
+	repair, derr := ui.DisplayError(ctx, err, p.logFile, p.client)
+	p.CaptureErr(ctx, derr)
+	if repair {
+		p.repairAfterError(ctx)
+		return
+	}
+	p.removeComponents(ctx)
 }
 
-func (p *Pinnacle) error(_ context.Context, err error) error {
-	return err
+// needsUserAction reports whether err is a problem with the user's
+// access, network or system settings rather than with the installation.
+func needsUserAction(err error) bool {
+	var (
+		accessErr *branchAccessError
+		branchErr *unknownBranchError
+		certErr   *untrustedCertError
+		skewErr   *clockSkewError
+	)
+	return errors.As(err, &accessErr) || errors.As(err, &branchErr) ||
+		errors.As(err, &certErr) || errors.As(err, &skewErr)
 }
 
-func (p *Pinnacle) fetchMetadata(ctx context.Context, url string) (*MetadataResponse, error) {
-	resp, err := p.getFromURL(ctx, url)
+func (p *Pinnacle) fetchMetadata(ctx context.Context, url string, header http.Header) (*MetadataResponse, error) {
+	resp, err := p.request(ctx, url, header, http.StatusOK)
 	if err != nil {
 		return nil, err
 	}
@@ -109,14 +250,25 @@ func (p *Pinnacle) fetchMetadata(ctx context.Context, url string) (*MetadataResp
 		p.CaptureErr(ctx, resp.Body.Close())
 	}()
 
+	err = checkCaptivePortal(url, resp, true)
+	if err != nil {
+		return nil, err
+	}
+
 	p.Breadcrumb(ctx, "decoding response from "+url)
 
-	err = json.NewDecoder(resp.Body).Decode(&metadataResponse)
+	var meta MetadataResponse
+	err = json.NewDecoder(resp.Body).Decode(&meta)
 	if err != nil {
 		return nil, err
 	}
 
-	return &metadataResponse, nil
+	err = meta.validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid metadata from %s: %w", url, err)
+	}
+
+	return &meta, nil
 }
 
 func (p *Pinnacle) fileHashMatches(ctx context.Context, hash string, path string) (bool, error) {
@@ -128,13 +280,11 @@ func (p *Pinnacle) fileHashMatches(ctx context.Context, hash string, path string
 		p.CaptureErr(ctx, file.Close())
 	}()
 
-	sha := sha1.New()
-	_, err = io.Copy(sha, file)
+	result, err := hashReader(file)
 	if err != nil {
 		return false, err
 	}
 
-	result := hex.EncodeToString(sha.Sum(nil))
 	if result == hash {
 		return true, nil
 	}
@@ -142,53 +292,64 @@ func (p *Pinnacle) fileHashMatches(ctx context.Context, hash string, path string
 	return false, fmt.Errorf("hash mismatch: got %s expected %s", result, hash)
 }
 
-func (p *Pinnacle) checkLauncher(ctx context.Context) error {
+// checkLauncher fetches the launcher metadata and returns it along with
+// errMissingLauncher if launcher.jar needs to be (re)downloaded.
+func (p *Pinnacle) checkLauncher(ctx context.Context) (*MetadataResponse, error) {
 	pt := ui.NewProgressTask("Preparing launcher...")
+	defer pt.Done()
 
 	p.Breadcrumb(ctx, "fetching metadata from /pinnacle")
 	pt.UpdateProgress(0.20)
 
-	launcher, err := p.fetchMetadata(ctx, MetadataURL+"/pinnacle?branch="+p.branch)
+	endpoint := MetadataURL + "/pinnacle?branch=" + p.branch
+	if p.config.LauncherVersion != "" {
+		endpoint += "&version=" + url.QueryEscape(p.config.LauncherVersion)
+	}
+	launcher, err := p.componentMetadata(ctx, "launcher", endpoint, p.authHeader(endpoint))
+	if errors.Is(err, errNotFound) && p.config.LauncherVersion != "" {
+		return nil, fmt.Errorf("launcher version %s isn't available on the %s branch", p.config.LauncherVersion, p.branch)
+	}
 	if err != nil {
-		return err
+		return nil, p.branchAccess(err)
 	}
 	pt.UpdateProgress(0.60)
 
-	targetPath := p.alpinePath("launcher.jar")
+	targetPath := p.launcherPath()
 	if !fileExists(targetPath) {
 		p.Breadcrumb(ctx, "missing launcher.jar")
-		return errMissingLauncher
+		return launcher, errMissingLauncher
 	}
 
 	if validHash, _ := p.fileHashMatches(ctx, launcher.Hash, targetPath); !validHash {
 		p.Breadcrumb(ctx, "failed checksum validation")
-		return errMissingLauncher
+		return launcher, errMissingLauncher
 	}
 
 	p.Breadcrumb(ctx, "finished checkLauncher (jar existed)")
-	return nil
+	return launcher, nil
 }
 
-func (p *Pinnacle) downloadLauncher(ctx context.Context) error {
+func (p *Pinnacle) downloadLauncher(ctx context.Context, launcher *MetadataResponse) error {
 	pt := ui.NewProgressTask("Downloading launcher...")
-	dest := p.alpinePath("launcher.jar")
+	defer pt.Done()
+	dest := p.launcherPath()
 
-	err := p.downloadFile(ctx, metadataResponse.URL, dest, pt)
+	err := p.downloadFile(ctx, launcher.URL, p.authHeader(launcher.URL), dest, launcher.Size, pt)
 	if err != nil {
 		return err
 	}
 
 	var validHash bool
-	if validHash, err = p.fileHashMatches(ctx, metadataResponse.Hash, dest); !validHash {
+	if validHash, err = p.fileHashMatches(ctx, launcher.Hash, dest); !validHash {
 		p.Breadcrumb(ctx, fmt.Sprintf("hash mismatch after download (retry): %v", err), slog.LevelError)
 
 		_ = os.RemoveAll(dest)
-		err = p.downloadFile(ctx, metadataResponse.URL, dest, pt)
+		err = p.downloadFile(ctx, launcher.URL, p.authHeader(launcher.URL), dest, launcher.Size, pt)
 		if err != nil {
 			return err
 		}
 
-		if validHash, err = p.fileHashMatches(ctx, metadataResponse.Hash, dest); !validHash {
+		if validHash, err = p.fileHashMatches(ctx, launcher.Hash, dest); !validHash {
 			p.Breadcrumb(ctx, fmt.Sprintf("hash mismatch after download: %v", err), slog.LevelError)
 			return err
 		}
@@ -199,29 +360,40 @@ func (p *Pinnacle) downloadLauncher(ctx context.Context) error {
 	return nil
 }
 
-func (p *Pinnacle) checkJava(ctx context.Context) error {
+// checkJava fetches the JRE metadata and returns it along with
+// errMissingJava if the runtime needs to be (re)installed.
+func (p *Pinnacle) checkJava(ctx context.Context) (*MetadataResponse, error) {
 	pt := ui.NewProgressTask("Preparing Java runtime...")
-	path := p.alpinePath("jre", "17")
-
-	p.Breadcrumb(ctx, "mkdir "+path)
-	err := os.MkdirAll(path, os.ModePerm)
-	if err != nil {
-		return err
-	}
+	defer pt.Done()
 	pt.UpdateProgress(0.20)
 
 	endpoint := fmt.Sprintf("%s/jre?version=17&os=%s&arch=%s", MetadataURL, p.os, p.arch)
 	p.Breadcrumb(ctx, "fetching manifest from "+endpoint)
-	jre, err := p.fetchMetadata(ctx, endpoint)
+	jre, err := p.componentMetadata(ctx, "java", endpoint, nil)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if jre.Name == "" {
+		return nil, errors.New("invalid metadata from " + endpoint + ": missing name")
+	}
+	pt.UpdateProgress(0.50)
+
+	err = p.verifyJava(ctx, jre, pt)
+	if err != nil {
+		return jre, err
 	}
 
+	p.Breadcrumb(ctx, "finished checkJava (existed)")
+	return jre, nil
+}
+
+// verifyJava checks that the installed runtime matches jre,
+// returning errMissingJava if it doesn't.
+func (p *Pinnacle) verifyJava(ctx context.Context, jre *MetadataResponse, pt *ui.ProgressiveTask) error {
 	var data []byte
 	var manifest JavaManifest
 	javaPath := p.alpinePath("jre", "17", "extracted", "bin", p.os.javaExecutable())
 	manifestPath := p.alpinePath("jre", "17", "version.json")
-	pt.UpdateProgress(0.50)
 
 	if !fileExists(javaPath) {
 		p.Breadcrumb(ctx, "missing java executable")
@@ -235,7 +407,7 @@ func (p *Pinnacle) checkJava(ctx context.Context) error {
 	}
 	pt.UpdateProgress(0.76)
 
-	data, err = os.ReadFile(manifestPath)
+	data, err := os.ReadFile(manifestPath)
 	if err != nil {
 		p.Breadcrumb(ctx, "failed to read manifest file")
 		return errMissingJava
@@ -253,12 +425,11 @@ func (p *Pinnacle) checkJava(ctx context.Context) error {
 		return errMissingJava
 	}
 
-	p.Breadcrumb(ctx, "finished checkJava (existed)")
 	return nil
 }
 
-func (p *Pinnacle) downloadJava(ctx context.Context) error {
-	archivePath := p.alpinePath("jre", "17", metadataResponse.Name)
+func (p *Pinnacle) downloadJava(ctx context.Context, jre *MetadataResponse) error {
+	archivePath := p.cachePath("jre", "17", jre.Name)
 	extractedPath := p.alpinePath("jre", "17", "extracted")
 	manifestPath := p.alpinePath("jre", "17", "version.json")
 
@@ -266,13 +437,22 @@ func (p *Pinnacle) downloadJava(ctx context.Context) error {
 	p.CaptureErr(ctx, os.RemoveAll(extractedPath))
 	p.CaptureErr(ctx, os.RemoveAll(manifestPath))
 
+	for _, path := range []string{p.alpinePath("jre", "17"), p.cachePath("jre", "17")} {
+		p.Breadcrumb(ctx, "mkdir "+path)
+		if err := os.MkdirAll(path, os.ModePerm); err != nil {
+			return err
+		}
+	}
+
 	pt := ui.NewProgressTask("Downloading Java...")
-	err := p.downloadFile(ctx, metadataResponse.URL, archivePath, pt)
+	err := p.downloadLargeFile(ctx, jre.URL, p.authHeader(jre.URL), archivePath, jre.Size, jre.Hash, pt)
+	pt.Done()
 	if err != nil {
 		return err
 	}
 
 	pt = ui.NewProgressTask("Extracting Java...")
+	defer pt.Done()
 
 	err = p.extractArchive(ctx, archivePath, extractedPath, pt)
 	if err != nil {
@@ -281,7 +461,12 @@ func (p *Pinnacle) downloadJava(ctx context.Context) error {
 
 	_ = os.Chmod(p.alpinePath("jre", "17", "extracted", "bin", p.os.javaExecutable()), 0o755)
 
-	data, err := json.Marshal(JavaManifest{Hash: metadataResponse.Hash, Size: metadataResponse.Size})
+	files, err := hashTree(extractedPath)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(JavaManifest{Hash: jre.Hash, Size: jre.Size, Files: files})
 	if err != nil {
 		return err
 	}
@@ -299,14 +484,15 @@ func (p *Pinnacle) downloadJava(ctx context.Context) error {
 
 func (p *Pinnacle) startLauncher(ctx context.Context) error {
 	pt := ui.NewProgressTask("Starting launcher...")
+	defer pt.Done()
 	pt.UpdateProgress(0.50)
 
-	jarPath := p.alpinePath("launcher.jar")
+	jarPath := p.launcherPath()
 	jrePath := p.alpinePath("jre", "17", "extracted", "bin", p.os.javaExecutable())
 
 	args := []string{
-		"-Xms256M",
-		"-Xmx256M",
+		"-Xms" + p.config.JavaMinHeap,
+		"-Xmx" + p.config.JavaMaxHeap,
 	}
 
 	if p.os == Mac {
@@ -375,32 +561,11 @@ func (p *Pinnacle) Breadcrumb(ctx context.Context, desc string, level ...slog.Le
 }
 
 // alpinePath returns the absolute path of Alpine Client's
-// data directory based on the user's operating system.
+// data directory, chosen by resolveDataDir.
 //
 // Optionally, pass in sub-folder/file names to add
 // them to the returned path.
 // - Example: p.alpinePath("jre", "17", "version.json")
-//
-// Windows - %AppData%\.alpineclient
-// Mac - $HOME/Library/Application Support/alpineclient
-// Linux - $HOME/.alpineclient
-//
-// note: The missing '.' for macOS is intentional.
 func (p *Pinnacle) alpinePath(subs ...string) string {
-	var baseDir string
-	var dirs []string
-
-	switch p.os {
-	case Windows:
-		baseDir = os.Getenv("AppData")
-		dirs = append(dirs, baseDir, ".alpineclient")
-	case Mac:
-		baseDir = os.Getenv("HOME")
-		dirs = append(dirs, baseDir, "Library", "Application Support", "alpineclient")
-	case Linux:
-		baseDir = os.Getenv("HOME")
-		dirs = append(dirs, baseDir, ".alpineclient")
-	}
-
-	return filepath.Join(append(dirs, subs...)...)
+	return filepath.Join(append([]string{p.dataDir}, subs...)...)
 }
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestNeedsUserAction(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "expired token", err: &branchAccessError{branch: "beta", hadToken: true}, want: true},
+		{name: "unknown branch", err: &unknownBranchError{branch: "betaa"}, want: true},
+		{name: "untrusted certificate", err: &untrustedCertError{host: "example.com"}, want: true},
+		{name: "clock skew", err: &clockSkewError{skew: 72 * time.Hour}, want: true},
+		{name: "wrapped", err: fmt.Errorf("launcher: %w", &branchAccessError{branch: "beta"}), want: true},
+		{name: "captive portal", err: &captivePortalError{}, want: false},
+		{name: "corrupted download", err: errTruncatedDownload, want: false},
+		{name: "other", err: errors.New("internet failure"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := needsUserAction(tt.err); got != tt.want {
+				t.Errorf("needsUserAction(%v) = %t, want %t", tt.err, got, tt.want)
+			}
+		})
+	}
+}
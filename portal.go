@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// captivePortalError is returned when a response looks like a
+// network login page (hotel, school or airport Wi-Fi) instead of
+// the content that was requested.
+type captivePortalError struct {
+	url    string // plain-http page the portal can intercept to show its sign-in form
+	reason string
+}
+
+func (e *captivePortalError) Error() string {
+	return "you appear to be behind a login page: " + e.reason
+}
+
+// checkCaptivePortal inspects resp for signs of a captive portal: an
+// HTML body or, when expectJSON is set (metadata requests), a redirect
+// to a different host or any body that isn't JSON. Artifact downloads
+// may legitimately redirect to a CDN. The response body is not consumed.
+func checkCaptivePortal(requested string, resp *http.Response, expectJSON bool) error {
+	original, err := url.Parse(requested)
+	if err != nil {
+		return err
+	}
+
+	// The portal can't intercept https without a certificate error, so
+	// point the browser at a plain-http page on the same host instead.
+	probe := "http://" + original.Host + "/generate_204"
+
+	final := resp.Request.URL
+	if expectJSON && final.Hostname() != original.Hostname() {
+		return &captivePortalError{
+			url:    probe,
+			reason: fmt.Sprintf("request to %s was redirected to %s", original.Hostname(), final.Hostname()),
+		}
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch {
+	case mediaType == "text/html" || looksLikeHTML(resp):
+		return &captivePortalError{url: probe, reason: "received a web page from " + original.Hostname()}
+	case expectJSON && mediaType != "" && !strings.HasSuffix(mediaType, "json"):
+		return &captivePortalError{
+			url:    probe,
+			reason: fmt.Sprintf("received %s instead of JSON from %s", mediaType, original.Hostname()),
+		}
+	}
+	return nil
+}
+
+// peekedBody replays bytes buffered while sniffing the response.
+type peekedBody struct {
+	*bufio.Reader
+	io.Closer
+}
+
+// looksLikeHTML sniffs the start of the body without consuming it.
+func looksLikeHTML(resp *http.Response) bool {
+	br := bufio.NewReader(resp.Body)
+	resp.Body = peekedBody{Reader: br, Closer: resp.Body}
+
+	head, _ := br.Peek(512)
+	start := strings.ToLower(strings.TrimSpace(string(head)))
+	return strings.HasPrefix(start, "<!doctype html") || strings.HasPrefix(start, "<html")
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCheckCaptivePortal(t *testing.T) {
+	const requested = "https://metadata.alpineclient.com/pinnacle"
+
+	tests := []struct {
+		name        string
+		final       string // URL after redirects, requested if empty
+		contentType string
+		body        string
+		expectJSON  bool
+		wantReason  string // empty if no portal is expected
+	}{
+		{name: "json", contentType: "application/json", body: `{"url":""}`, expectJSON: true},
+		{name: "json with charset", contentType: "application/json; charset=utf-8", body: `{}`, expectJSON: true},
+		{name: "json without content type", body: `{}`, expectJSON: true},
+		{name: "download", contentType: "application/octet-stream", body: "PK\x03\x04"},
+		{
+			name:        "html content type",
+			contentType: "text/html; charset=utf-8",
+			body:        "Sign in",
+			wantReason:  "received a web page from metadata.alpineclient.com",
+		},
+		{
+			name:       "html body without content type",
+			body:       "\n  <!DOCTYPE html><html><body>Sign in</body></html>",
+			wantReason: "received a web page",
+		},
+		{name: "html body as json", contentType: "application/json", body: "<HTML>", expectJSON: true, wantReason: "received a web page"},
+		{
+			name:        "json redirected to another host",
+			final:       "http://login.hotel.example/portal",
+			contentType: "application/json",
+			body:        `{}`,
+			expectJSON:  true,
+			wantReason:  "redirected to login.hotel.example",
+		},
+		{name: "download redirected to a cdn", final: "https://cdn.example.com/jre.zip", contentType: "application/zip", body: "PK"},
+		{
+			name:        "plain text instead of json",
+			contentType: "text/plain",
+			body:        "Please log in",
+			expectJSON:  true,
+			wantReason:  "received text/plain instead of JSON",
+		},
+		{name: "plain text download", contentType: "text/plain", body: "notes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			final := tt.final
+			if final == "" {
+				final = requested
+			}
+			finalURL, err := url.Parse(final)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp := &http.Response{
+				Header:  http.Header{},
+				Body:    io.NopCloser(strings.NewReader(tt.body)),
+				Request: &http.Request{URL: finalURL},
+			}
+			if tt.contentType != "" {
+				resp.Header.Set("Content-Type", tt.contentType)
+			}
+
+			err = checkCaptivePortal(requested, resp, tt.expectJSON)
+
+			var portalErr *captivePortalError
+			switch {
+			case tt.wantReason == "" && err != nil:
+				t.Fatalf("checkCaptivePortal() error = %v, want none", err)
+			case tt.wantReason != "" && !errors.As(err, &portalErr):
+				t.Fatalf("checkCaptivePortal() error = %v, want a captive portal", err)
+			case tt.wantReason != "":
+				if !strings.Contains(portalErr.reason, tt.wantReason) {
+					t.Errorf("reason = %q, want it to contain %q", portalErr.reason, tt.wantReason)
+				}
+				if portalErr.url != "http://metadata.alpineclient.com/generate_204" {
+					t.Errorf("login page = %q, want the plain-http probe on the requested host", portalErr.url)
+				}
+			}
+		})
+	}
+}
+
+func TestLooksLikeHTMLKeepsBody(t *testing.T) {
+	body := "<html>" + strings.Repeat("x", 1000)
+	resp := &http.Response{Body: io.NopCloser(strings.NewReader(body))}
+
+	if !looksLikeHTML(resp) {
+		t.Error("looksLikeHTML() = false, want true")
+	}
+	got, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != body {
+		t.Errorf("body after sniffing has %d bytes, want all %d", len(got), len(body))
+	}
+	if err = resp.Body.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// sampleInterval is how often the throughput estimate is refreshed.
+	sampleInterval = 500 * time.Millisecond
+	// smoothing is the weight given to the newest throughput sample.
+	smoothing = 0.3
+)
+
+// transferStats estimates throughput and time remaining for a
+// download from the number of bytes written so far.
+type transferStats struct {
+	last      time.Time
+	limit     string
+	text      string
+	total     int64
+	lastBytes int64
+	rate      float64 // smoothed bytes per second
+}
+
+func (p *Pinnacle) newTransferStats(total int64) *transferStats {
+	s := &transferStats{
+		total: total,
+		last:  time.Now(),
+	}
+	if p.limiter != nil {
+		s.limit = "limited to " + formatBytes(int64(p.limiter.rate)) + "/s"
+	}
+	return s
+}
+
+// note returns the detail text for the progress dialog, e.g.
+// "12.3 MB / 45.0 MB, 2.1 MB/s, 16s left".
+func (s *transferStats) note(written int64) string {
+	now := time.Now()
+	if elapsed := now.Sub(s.last); elapsed >= sampleInterval {
+		sample := float64(written-s.lastBytes) / elapsed.Seconds()
+		if s.rate == 0 {
+			s.rate = sample
+		} else {
+			s.rate = smoothing*sample + (1-smoothing)*s.rate
+		}
+		s.last = now
+		s.lastBytes = written
+	} else if s.text != "" {
+		return s.text
+	}
+
+	parts := make([]string, 0, 4)
+	if s.total > 0 {
+		parts = append(parts, fmt.Sprintf("%s / %s", formatBytes(written), formatBytes(s.total)))
+	} else {
+		parts = append(parts, formatBytes(written))
+	}
+	if s.rate > 0 {
+		parts = append(parts, formatBytes(int64(s.rate))+"/s")
+		if s.total > written {
+			eta := time.Duration(float64(s.total-written) / s.rate * float64(time.Second))
+			parts = append(parts, eta.Round(time.Second).String()+" left")
+		}
+	}
+	if s.limit != "" {
+		parts = append(parts, s.limit)
+	}
+
+	s.text = strings.Join(parts, ", ")
+	return s.text
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestTransferStatsNote(t *testing.T) {
+	tests := []struct {
+		name    string
+		total   int64
+		limit   string
+		elapsed time.Duration // since the previous sample at 0 bytes
+		written int64
+		want    string
+	}{
+		{name: "before first sample", total: 4_000_000, elapsed: 0, written: 1_000_000, want: "1.0 MB / 4.0 MB"},
+		{name: "with rate and eta", total: 10_000_000, elapsed: time.Second, written: 2_500_000, want: "2.5 MB / 10.0 MB, 2.5 MB/s, 3s left"},
+		{name: "finished", total: 4_000_000, elapsed: time.Second, written: 4_000_000, want: "4.0 MB / 4.0 MB, 4.0 MB/s"},
+		{name: "unknown size", elapsed: 2 * time.Second, written: 1_500_000, want: "1.5 MB, 750.0 kB/s"},
+		{
+			name:    "rate limited",
+			total:   10_000_000,
+			limit:   "limited to 2.5 MB/s",
+			elapsed: time.Second,
+			written: 2_500_000,
+			want:    "2.5 MB / 10.0 MB, 2.5 MB/s, 3s left, limited to 2.5 MB/s",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &transferStats{total: tt.total, limit: tt.limit, last: time.Now().Add(-tt.elapsed)}
+			if got := s.note(tt.written); got != tt.want {
+				t.Errorf("note(%d) = %q, want %q", tt.written, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransferStatsNoteSmoothing(t *testing.T) {
+	s := &transferStats{total: 10_000_000, last: time.Now().Add(-time.Second)}
+	first := s.note(2_500_000) // 2.5 MB/s
+
+	// Within the sample interval the previous text is kept.
+	if got := s.note(3_000_000); got != first {
+		t.Errorf("note() between samples = %q, want %q", got, first)
+	}
+
+	s.last = time.Now().Add(-time.Second)
+	s.note(6_500_000) // 4 MB/s sample
+	if want := smoothing*4_000_000 + (1-smoothing)*2_500_000; math.Abs(s.rate-want) > want/100 {
+		t.Errorf("smoothed rate = %.0f, want about %.0f", s.rate, want)
+	}
+}
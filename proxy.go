@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProxyConfig overrides proxy detection from config.json.
+type ProxyConfig struct {
+	URL      string   `json:"url,omitempty"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	Ignore   []string `json:"ignore_hosts,omitempty"`
+}
+
+// proxySettings is a resolved proxy configuration.
+type proxySettings struct {
+	http   *url.URL
+	https  *url.URL
+	source string
+	ignore []string
+}
+
+// proxyFor returns the proxy to use for req, or nil for a direct connection.
+// As with HTTPS_PROXY, an unset https proxy doesn't fall back to the http
+// one, so a desktop with only an http proxy connects directly for https.
+func (s *proxySettings) proxyFor(req *http.Request) (*url.URL, error) {
+	host := req.URL.Hostname()
+	for _, pattern := range s.ignore {
+		if matchesIgnoreHost(pattern, host) {
+			return nil, nil //nolint:nilnil // nil URL means no proxy
+		}
+	}
+	if req.URL.Scheme == "https" {
+		return s.https, nil
+	}
+	return s.http, nil
+}
+
+// matchesIgnoreHost reports whether host matches an ignore-hosts entry.
+// Entries may be hostnames, domain suffixes ("*.corp" or ".corp"), IPs or CIDR ranges.
+func matchesIgnoreHost(pattern string, host string) bool {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	host = strings.ToLower(host)
+	if pattern == "" {
+		return false
+	}
+	if pattern == "*" || pattern == host {
+		return true
+	}
+	if _, network, err := net.ParseCIDR(pattern); err == nil {
+		ip := net.ParseIP(host)
+		return ip != nil && network.Contains(ip)
+	}
+	suffix := strings.TrimPrefix(pattern, "*")
+	if strings.HasPrefix(suffix, ".") {
+		return strings.HasSuffix(host, suffix) || host == suffix[1:]
+	}
+	return false
+}
+
+// configureProxy selects the proxy used by httpClient. The config file
+// override wins, then the desktop settings (GNOME or KDE on Linux),
+// falling back to the HTTP(S)_PROXY environment variables.
+func (p *Pinnacle) configureProxy(override *ProxyConfig) error {
+	transport, ok := httpClient.Transport.(*http.Transport)
+	if !ok {
+		return errors.New("unexpected http transport")
+	}
+
+	settings, err := proxyFromConfig(override)
+	if err != nil {
+		return err
+	}
+	if settings == nil && p.os == Linux {
+		settings = desktopProxy()
+	}
+	if settings == nil {
+		p.proxySource = "environment"
+		transport.Proxy = http.ProxyFromEnvironment
+		return nil
+	}
+
+	p.proxySource = settings.source
+	p.logger.Info("using proxy settings from " + settings.source)
+	transport.Proxy = settings.proxyFor
+	return nil
+}
+
+func proxyFromConfig(cfg *ProxyConfig) (*proxySettings, error) {
+	if cfg == nil || cfg.URL == "" {
+		return nil, nil //nolint:nilnil // no override configured
+	}
+	u, err := parseProxyURL(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Username != "" {
+		u.User = url.UserPassword(cfg.Username, cfg.Password)
+	}
+	return &proxySettings{http: u, https: u, ignore: cfg.Ignore, source: "pinnacle config"}, nil
+}
+
+func parseProxyURL(raw string) (*url.URL, error) {
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, errors.New("proxy URL is missing a host")
+	}
+	return u, nil
+}
+
+// desktopProxy reads the proxy configured in the desktop environment's
+// settings. It returns nil if none is configured or it cannot be read.
+func desktopProxy() *proxySettings {
+	desktop := strings.ToUpper(os.Getenv("XDG_CURRENT_DESKTOP"))
+	if strings.Contains(desktop, "KDE") {
+		if s := kdeProxy(); s != nil {
+			return s
+		}
+	}
+	return gnomeProxy()
+}
+
+// gnomeProxy reads org.gnome.system.proxy via gsettings. Only manual
+// mode is supported; automatic (PAC) configuration is ignored.
+func gnomeProxy() *proxySettings {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	get := func(schema string, key string) string {
+		out, err := exec.CommandContext(ctx, "gsettings", "get", schema, key).Output()
+		if err != nil {
+			return ""
+		}
+		return strings.Trim(strings.TrimSpace(string(out)), "'")
+	}
+
+	if get("org.gnome.system.proxy", "mode") != "manual" {
+		return nil
+	}
+
+	proxy := func(schema string) *url.URL {
+		host := get(schema, "host")
+		port := get(schema, "port")
+		if host == "" || port == "" || port == "0" {
+			return nil
+		}
+		u := &url.URL{Scheme: "http", Host: net.JoinHostPort(host, port)}
+		if get(schema, "use-authentication") == "true" {
+			u.User = url.UserPassword(get(schema, "authentication-user"), get(schema, "authentication-password"))
+		}
+		return u
+	}
+
+	s := &proxySettings{
+		http:   proxy("org.gnome.system.proxy.http"),
+		https:  proxy("org.gnome.system.proxy.https"),
+		ignore: parseGSettingsList(get("org.gnome.system.proxy", "ignore-hosts")),
+		source: "GNOME settings",
+	}
+	if s.http == nil && s.https == nil {
+		return nil
+	}
+	return s
+}
+
+// parseGSettingsList parses a GVariant string array such as
+// ['localhost', '127.0.0.0/8'] or @as [].
+func parseGSettingsList(v string) []string {
+	v = strings.TrimPrefix(v, "@as ")
+	v = strings.TrimSuffix(strings.TrimPrefix(v, "["), "]")
+
+	var items []string
+	for item := range strings.SplitSeq(v, ",") {
+		if item = strings.Trim(strings.TrimSpace(item), "'"); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// kdeProxy reads the [Proxy Settings] group of kioslaverc. Only manual
+// configuration (ProxyType=1) is supported, and reversed exception
+// lists ("use proxy only for") are treated as having no exceptions.
+func kdeProxy() *proxySettings {
+	configDir := os.Getenv("XDG_CONFIG_HOME")
+	if configDir == "" {
+		configDir = filepath.Join(os.Getenv("HOME"), ".config")
+	}
+
+	file, err := os.Open(filepath.Join(configDir, "kioslaverc"))
+	if err != nil {
+		return nil
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	values := make(map[string]string)
+	inGroup := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			inGroup = line == "[Proxy Settings]"
+			continue
+		}
+		if key, value, found := strings.Cut(line, "="); inGroup && found {
+			values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+
+	if values["ProxyType"] != "1" {
+		return nil
+	}
+
+	s := &proxySettings{
+		http:   parseKDEProxy(values["httpProxy"]),
+		https:  parseKDEProxy(values["httpsProxy"]),
+		source: "KDE settings",
+	}
+	if s.http == nil && s.https == nil {
+		return nil
+	}
+	if values["ReversedException"] != "true" {
+		s.ignore = strings.Split(values["NoProxyFor"], ",")
+	}
+	return s
+}
+
+// parseKDEProxy parses KDE proxy entries, which are either a URL
+// or a URL and port separated by a space ("http://proxy 8080").
+func parseKDEProxy(v string) *url.URL {
+	if v == "" {
+		return nil
+	}
+	host, port, found := strings.Cut(v, " ")
+	u, err := parseProxyURL(host)
+	if err != nil {
+		return nil
+	}
+	if _, perr := strconv.Atoi(port); found && perr == nil {
+		u.Host = net.JoinHostPort(u.Hostname(), port)
+	}
+	return u
+}
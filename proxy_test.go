@@ -0,0 +1,95 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+)
+
+func TestParseGSettingsList(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{input: "@as []", want: nil},
+		{input: "[]", want: nil},
+		{input: "['localhost']", want: []string{"localhost"}},
+		{input: "['localhost', '127.0.0.0/8', '::1']", want: []string{"localhost", "127.0.0.0/8", "::1"}},
+		{input: "['*.example.com','', 'intranet']", want: []string{"*.example.com", "intranet"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := parseGSettingsList(tt.input); !slices.Equal(got, tt.want) {
+				t.Errorf("parseGSettingsList(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchesIgnoreHost(t *testing.T) {
+	tests := []struct {
+		pattern string
+		host    string
+		want    bool
+	}{
+		{pattern: "", host: "example.com", want: false},
+		{pattern: "*", host: "example.com", want: true},
+		{pattern: "example.com", host: "example.com", want: true},
+		{pattern: " Example.COM ", host: "example.com", want: true},
+		{pattern: "example.com", host: "EXAMPLE.com", want: true},
+		{pattern: "example.com", host: "www.example.com", want: false},
+		{pattern: "*.example.com", host: "www.example.com", want: true},
+		{pattern: "*.example.com", host: "example.com", want: true},
+		{pattern: ".example.com", host: "a.b.example.com", want: true},
+		{pattern: ".example.com", host: "badexample.com", want: false},
+		{pattern: "10.0.0.0/8", host: "10.1.2.3", want: true},
+		{pattern: "10.0.0.0/8", host: "11.1.2.3", want: false},
+		{pattern: "10.0.0.0/8", host: "intranet", want: false},
+		{pattern: "::1/128", host: "::1", want: true},
+		{pattern: "example.*", host: "example.com", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.host, func(t *testing.T) {
+			if got := matchesIgnoreHost(tt.pattern, tt.host); got != tt.want {
+				t.Errorf("matchesIgnoreHost(%q, %q) = %v, want %v", tt.pattern, tt.host, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProxyFor(t *testing.T) {
+	httpProxy := &url.URL{Scheme: "http", Host: "proxy:3128"}
+	httpsProxy := &url.URL{Scheme: "http", Host: "secure-proxy:3128"}
+	tests := []struct {
+		name     string
+		settings proxySettings
+		url      string
+		want     *url.URL
+	}{
+		{name: "http", settings: proxySettings{http: httpProxy, https: httpsProxy}, url: "http://example.com", want: httpProxy},
+		{name: "https", settings: proxySettings{http: httpProxy, https: httpsProxy}, url: "https://example.com", want: httpsProxy},
+		{name: "https without https proxy", settings: proxySettings{http: httpProxy}, url: "https://example.com"},
+		{name: "http without http proxy", settings: proxySettings{https: httpsProxy}, url: "http://example.com"},
+		{
+			name:     "ignored host",
+			settings: proxySettings{http: httpProxy, https: httpsProxy, ignore: []string{"*.example.com"}},
+			url:      "https://cdn.example.com",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, tt.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := tt.settings.proxyFor(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("proxyFor(%s) = %v, want %v", tt.url, got, tt.want)
+			}
+		})
+	}
+}
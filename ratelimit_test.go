@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNewRateLimiter(t *testing.T) {
+	tests := []struct {
+		name    string
+		rate    int64
+		wantNil bool
+	}{
+		{name: "unlimited", rate: 0, wantNil: true},
+		{name: "negative", rate: -1, wantNil: true},
+		{name: "limited", rate: 1024},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newRateLimiter(tt.rate); (got == nil) != tt.wantNil {
+				t.Errorf("newRateLimiter(%d) = %v, want nil %v", tt.rate, got, tt.wantNil)
+			}
+		})
+	}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	const rate = 10_000 // bytes per second, starting with a full bucket
+
+	tests := []struct {
+		name    string
+		limiter *rateLimiter
+		reads   []int
+		minWait time.Duration
+		maxWait time.Duration
+	}{
+		{name: "nil limiter", limiter: nil, reads: []int{1 << 30}, maxWait: 50 * time.Millisecond},
+		{name: "within burst", limiter: newRateLimiter(rate), reads: []int{5_000, 5_000}, maxWait: 50 * time.Millisecond},
+		{name: "zero bytes", limiter: newRateLimiter(rate), reads: []int{0, -1}, maxWait: 50 * time.Millisecond},
+		{
+			name:    "over burst",
+			limiter: newRateLimiter(rate),
+			reads:   []int{rate, 2_000},
+			minWait: 150 * time.Millisecond,
+			maxWait: 1 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			for _, n := range tt.reads {
+				if err := tt.limiter.wait(context.Background(), n); err != nil {
+					t.Fatalf("wait(%d) error = %v", n, err)
+				}
+			}
+			if elapsed := time.Since(start); elapsed < tt.minWait || elapsed > tt.maxWait {
+				t.Errorf("waited %s, want between %s and %s", elapsed, tt.minWait, tt.maxWait)
+			}
+		})
+	}
+}
+
+func TestRateLimiterWaitCanceled(t *testing.T) {
+	l := newRateLimiter(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := l.wait(ctx, 1_000); !errors.Is(err, context.Canceled) {
+		t.Errorf("wait() error = %v, want context.Canceled", err)
+	}
+}
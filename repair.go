@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/alpine-client/pinnacle/ui"
+)
+
+// repairReport summarizes what a repair found and fixed.
+type repairReport struct {
+	lines   []string
+	removed []string
+}
+
+func (r *repairReport) String() string {
+	var b strings.Builder
+	for _, line := range r.lines {
+		b.WriteString(line + "\n")
+	}
+	switch len(r.removed) {
+	case 0:
+		b.WriteString("No temporary files found.\n")
+	default:
+		fmt.Fprintf(&b, "Removed %d temporary file(s).\n", len(r.removed))
+	}
+	return b.String()
+}
+
+// Repair fully verifies every component, reinstalls the ones that are
+// broken and clears temporary files left by interrupted installs.
+func (p *Pinnacle) Repair(ctx context.Context) (*repairReport, error) {
+	report := &repairReport{}
+
+	if err := p.registerManifestComponents(ctx); err != nil {
+		return report, err
+	}
+	components, err := orderComponents(p.components)
+	if err != nil {
+		return report, err
+	}
+
+	for _, c := range components {
+		cctx := p.client.NewContext(ctx, c.Name())
+		line, cerr := p.repairComponent(cctx, c)
+		if cerr != nil {
+			report.lines = append(report.lines, c.Name()+": repair failed")
+			return report, cerr
+		}
+		report.lines = append(report.lines, c.Name()+": "+line)
+	}
+
+	report.removed = p.clearTemporaryFiles(ctx)
+	return report, nil
+}
+
+func (p *Pinnacle) repairComponent(ctx context.Context, c Component) (string, error) {
+	meta, err := c.Check(ctx)
+	if err == nil {
+		verr := c.Verify(ctx, meta)
+		if verr == nil {
+			return "OK", nil
+		}
+		err = fmt.Errorf("%w: %w", errNeedsInstall, verr)
+	}
+	if !errors.Is(err, errNeedsInstall) {
+		return "", err
+	}
+
+	reason := strings.TrimPrefix(err.Error(), errNeedsInstall.Error()+": ")
+	p.Breadcrumb(ctx, fmt.Sprintf("repairing %s: %s", c.Name(), reason))
+	ui.Render(p.logger)
+
+	if err = c.Install(ctx, meta); err != nil {
+		return "", err
+	}
+	if err = c.Verify(ctx, meta); err != nil {
+		return "", err
+	}
+	return "fixed (" + reason + ")", nil
+}
+
+// repairAfterError runs a repair chosen from the error dialog and shows
+// the result. The repair runs detached from ctx, since the run that
+// failed may already have been cancelled. A failed repair leaves the installation in
+// place so it can be repaired again later.
+func (p *Pinnacle) repairAfterError(ctx context.Context) {
+	ctx = context.WithoutCancel(ctx)
+	report, err := p.Repair(ctx)
+	if err != nil {
+		p.CaptureErr(ctx, ui.DisplayRepairFailed(ctx, err, p.client))
+		return
+	}
+	ui.DisplayRepairSummary(report.String() + "\nStart Alpine Client again to play.")
+}
+
+// clearTemporaryFiles removes leftovers of interrupted installs: JRE
+// archives and partial downloads. It returns the removed paths.
+func (p *Pinnacle) clearTemporaryFiles(ctx context.Context) []string {
+	var removed []string
+
+	remove := func(path string) {
+		if err := os.RemoveAll(path); err != nil {
+			p.CaptureErr(ctx, err)
+			return
+		}
+		p.Breadcrumb(ctx, "removed temporary file "+path)
+		removed = append(removed, path)
+	}
+
+	// Anything besides the extracted runtime and its manifest is a leftover archive.
+	for _, dir := range slices.Compact([]string{p.alpinePath("jre", "17"), p.cachePath("jre", "17")}) {
+		entries, _ := os.ReadDir(dir)
+		for _, entry := range entries {
+			if entry.Name() != "extracted" && entry.Name() != "version.json" {
+				remove(filepath.Join(dir, entry.Name()))
+			}
+		}
+	}
+
+	// Only look next to files Pinnacle installed; anything else in a
+	// component's target belongs to someone else.
+	for _, c := range p.components {
+		if _, ok := c.(*manifestComponent); !ok {
+			continue
+		}
+		for _, path := range c.Paths() {
+			for _, leftover := range []string{path + ".part", path + ".tmp"} {
+				if fileExists(leftover) {
+					remove(leftover)
+				}
+			}
+		}
+	}
+	return removed
+}
+
+// Clean removes temporary files and, with -all, every installed
+// component so that the next run downloads them again.
+func (p *Pinnacle) Clean(ctx context.Context, w io.Writer) error {
+	if err := p.registerManifestComponents(ctx); err != nil {
+		p.logger.WarnContext(ctx, "unable to list components, cleaning built-in components only: "+err.Error())
+	}
+
+	removed := p.clearTemporaryFiles(ctx)
+	if p.opts.all {
+		for _, c := range p.components {
+			for _, path := range c.Paths() {
+				if !fileExists(path) {
+					continue
+				}
+				if err := os.RemoveAll(path); err != nil {
+					return err
+				}
+				removed = append(removed, path)
+			}
+		}
+	}
+
+	for _, path := range removed {
+		_, _ = fmt.Fprintln(w, "removed", path)
+	}
+	_, _ = fmt.Fprintf(w, "Removed %d item(s).\n", len(removed))
+	return nil
+}
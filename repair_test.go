@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// brokenComponent passes its check but fails verification until installed.
+type brokenComponent struct {
+	fakeComponent
+	installErr error
+	installed  bool
+}
+
+func (c *brokenComponent) Install(context.Context, *MetadataResponse) error {
+	if c.installErr != nil {
+		return c.installErr
+	}
+	c.installed = true
+	return nil
+}
+
+func (c *brokenComponent) Verify(context.Context, *MetadataResponse) error {
+	if !c.installed {
+		return errors.New("2 file(s) missing or corrupted")
+	}
+	return nil
+}
+
+func TestRepairComponent(t *testing.T) {
+	errOffline := errors.New("internet failure")
+
+	tests := []struct {
+		name          string
+		component     Component
+		want          string
+		wantErr       error
+		wantInstalled bool
+	}{
+		{name: "intact", component: &fakeComponent{name: "java"}, want: "OK"},
+		{
+			name:          "corrupted",
+			component:     &brokenComponent{fakeComponent: fakeComponent{name: "java"}},
+			want:          "fixed (2 file(s) missing or corrupted)",
+			wantInstalled: true,
+		},
+		{
+			name:      "reinstall fails",
+			component: &brokenComponent{fakeComponent: fakeComponent{name: "java"}, installErr: errOffline},
+			wantErr:   errOffline,
+		},
+		{name: "check fails", component: &fakeComponent{name: "java", err: errOffline}, wantErr: errOffline},
+		{
+			name:          "missing",
+			component:     &brokenComponent{fakeComponent: fakeComponent{name: "java", err: fmt.Errorf("%w: missing java", errNeedsInstall)}},
+			want:          "fixed (missing java)",
+			wantInstalled: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := testPinnacle(t.TempDir())
+
+			got, err := p.repairComponent(context.Background(), tt.component)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("repairComponent() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("repairComponent() = %q, want %q", got, tt.want)
+			}
+			if bc, ok := tt.component.(*brokenComponent); ok && bc.installed != tt.wantInstalled {
+				t.Errorf("installed = %t, want %t", bc.installed, tt.wantInstalled)
+			}
+		})
+	}
+}
+
+func TestClearTemporaryFiles(t *testing.T) {
+	dir := t.TempDir()
+	p := testPinnacle(dir)
+	mods := &manifestComponent{p: p, desc: componentDescriptor{Name: "mods", Target: "mods"}}
+	p.register(mods)
+
+	for _, path := range []string{
+		"jre/17/version.json", "jre/17/extracted/bin/java", "jre/17/jre.zip",
+		"mods/a.jar", "mods/a.jar.part", "mods/b.jar.part",
+	} {
+		path = p.alpinePath(strings.Split(path, "/")...)
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := mods.writeInstalled(map[string]string{"a.jar": emptySHA1}); err != nil {
+		t.Fatal(err)
+	}
+
+	removed := p.clearTemporaryFiles(context.Background())
+	slices.Sort(removed)
+	want := []string{p.alpinePath("jre", "17", "jre.zip"), p.alpinePath("mods", "a.jar.part")}
+	if !slices.Equal(removed, want) {
+		t.Errorf("clearTemporaryFiles() = %q, want %q", removed, want)
+	}
+	for _, kept := range []string{"jre/17/extracted/bin/java", "jre/17/version.json", "mods/a.jar", "mods/b.jar.part"} {
+		if !fileExists(p.alpinePath(strings.Split(kept, "/")...)) {
+			t.Errorf("%s was removed", kept)
+		}
+	}
+}
+
+func TestRepairReportString(t *testing.T) {
+	report := &repairReport{lines: []string{"java: OK", "launcher: fixed (missing launcher)"}}
+	if got, want := report.String(), "java: OK\nlauncher: fixed (missing launcher)\nNo temporary files found.\n"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	report.removed = []string{"a.part", "b.part"}
+	if got := report.String(); !strings.HasSuffix(got, "Removed 2 temporary file(s).\n") {
+		t.Errorf("String() = %q, want the removed count", got)
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/alpine-client/pinnacle/ui"
+)
+
+// segmentThreshold is the minimum size for a file
+// to be downloaded over multiple connections.
+const segmentThreshold = 32 << 20 // 32 MiB
+
+// progressWriter forwards writes to a segment of the destination
+// file while reporting the combined progress of all segments.
+type progressWriter struct {
+	dst     io.Writer
+	mu      *sync.Mutex
+	written *int64
+	stats   *transferStats
+	pt      *ui.ProgressiveTask
+}
+
+func (w *progressWriter) Write(b []byte) (int, error) {
+	n, err := w.dst.Write(b)
+
+	w.mu.Lock()
+	*w.written += int64(n)
+	if w.pt != nil {
+		w.pt.SetNote(w.stats.note(*w.written))
+		w.pt.UpdateProgress(float64(*w.written) / float64(w.stats.total))
+	}
+	w.mu.Unlock()
+
+	return n, err
+}
+
+// downloadLargeFile downloads url to path using p.segments concurrent
+// range requests when the file is large enough and the server supports
+// it, falling back to a regular download otherwise. Either way, the
+// downloaded file is verified against hash.
+func (p *Pinnacle) downloadLargeFile(
+	ctx context.Context, url string, header http.Header, path string, size uint32, hash string, pt *ui.ProgressiveTask,
+) error {
+	var err error
+	if p.segments < 2 || size < segmentThreshold || !p.supportsRanges(ctx, url, header, size) {
+		err = p.downloadFile(ctx, url, header, path, size, pt)
+	} else {
+		err = p.downloadSegmentedFile(ctx, url, header, path, size, pt)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = p.fileHashMatches(ctx, hash, path)
+	return err
+}
+
+// downloadSegmentedFile preallocates path and fills it
+// with p.segments concurrent range requests.
+func (p *Pinnacle) downloadSegmentedFile(
+	ctx context.Context, url string, header http.Header, path string, size uint32, pt *ui.ProgressiveTask,
+) error {
+	p.Breadcrumb(ctx, fmt.Sprintf("downloading %s in %d segments", url, p.segments))
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		p.CaptureErr(ctx, file.Close())
+	}()
+
+	err = file.Truncate(int64(size))
+	if err != nil {
+		return err
+	}
+
+	return p.downloadSegments(ctx, url, header, file, int64(size), pt)
+}
+
+func (p *Pinnacle) downloadSegments(
+	ctx context.Context, url string, header http.Header, file *os.File, size int64, pt *ui.ProgressiveTask,
+) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		written  int64
+		firstErr error
+	)
+	stats := p.newTransferStats(size)
+	if pt != nil {
+		defer pt.SetNote("")
+	}
+
+	chunk := size / int64(p.segments)
+	for i := range p.segments {
+		start := int64(i) * chunk
+		end := start + chunk - 1
+		if i == p.segments-1 {
+			end = size - 1
+		}
+
+		w := &progressWriter{
+			dst:     io.NewOffsetWriter(file, start),
+			mu:      &mu,
+			written: &written,
+			stats:   stats,
+			pt:      pt,
+		}
+
+		wg.Go(func() {
+			err := p.downloadSegment(ctx, url, header, start, end, w)
+			if err == nil {
+				return
+			}
+			mu.Lock()
+			if firstErr == nil {
+				firstErr = err
+				cancel()
+			}
+			mu.Unlock()
+		})
+	}
+	wg.Wait()
+
+	return firstErr
+}
+
+func (p *Pinnacle) downloadSegment(ctx context.Context, url string, header http.Header, start int64, end int64, dst io.Writer) error {
+	header = header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+
+	resp, err := p.request(ctx, url, header, http.StatusPartialContent)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		p.CaptureErr(ctx, resp.Body.Close())
+	}()
+
+	return p.copyResponseWithProgress(ctx, dst, resp, end-start+1, nil)
+}
+
+// supportsRanges reports whether the server hosting url accepts
+// byte range requests and advertises the expected size.
+func (p *Pinnacle) supportsRanges(ctx context.Context, url string, header http.Header, size uint32) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return false
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("User-Agent", fmt.Sprintf("Pinnacle/%s (%s; %s)", version, p.os, p.arch))
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		p.Breadcrumb(ctx, "range support check failed: "+err.Error())
+		return false
+	}
+	p.CaptureErr(ctx, resp.Body.Close())
+
+	return resp.StatusCode == http.StatusOK &&
+		resp.Header.Get("Accept-Ranges") == "bytes" &&
+		resp.ContentLength == int64(size)
+}
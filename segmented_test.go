@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// segmentedPinnacle returns a test Pinnacle that downloads with
+// the given number of segments and doesn't retry failed requests.
+func segmentedPinnacle(t *testing.T, segments int) *Pinnacle {
+	t.Helper()
+	p := testPinnacle(t.TempDir())
+	p.segments = segments
+	p.config.Retries = new(int)
+	return p
+}
+
+// rangeServer serves body with range support and records
+// the Range header of every GET request.
+func rangeServer(t *testing.T, body []byte) (*httptest.Server, func() []string) {
+	t.Helper()
+	var (
+		mu     sync.Mutex
+		ranges []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			mu.Lock()
+			ranges = append(ranges, r.Header.Get("Range"))
+			mu.Unlock()
+		}
+		http.ServeContent(w, r, "artifact", time.Time{}, bytes.NewReader(body))
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		slices.Sort(ranges)
+		return slices.Clone(ranges)
+	}
+}
+
+func TestDownloadSegments(t *testing.T) {
+	body := bytes.Repeat([]byte("0123456789"), 100)
+
+	tests := []struct {
+		segments   int
+		wantRanges []string
+	}{
+		{segments: 2, wantRanges: []string{"bytes=0-499", "bytes=500-999"}},
+		{segments: 3, wantRanges: []string{"bytes=0-332", "bytes=333-665", "bytes=666-999"}},
+		{segments: 7, wantRanges: []string{
+			"bytes=0-141", "bytes=142-283", "bytes=284-425", "bytes=426-567",
+			"bytes=568-709", "bytes=710-851", "bytes=852-999",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d segments", tt.segments), func(t *testing.T) {
+			srv, ranges := rangeServer(t, body)
+			p := segmentedPinnacle(t, tt.segments)
+
+			file, err := os.Create(filepath.Join(t.TempDir(), "artifact"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = file.Close() }()
+
+			if err = p.downloadSegments(context.Background(), srv.URL, nil, file, int64(len(body)), nil); err != nil {
+				t.Fatalf("downloadSegments() error = %v", err)
+			}
+			if got := ranges(); !slices.Equal(got, tt.wantRanges) {
+				t.Errorf("requested ranges %q, want %q", got, tt.wantRanges)
+			}
+			got, err := os.ReadFile(file.Name())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, body) {
+				t.Error("reassembled file differs from the served body")
+			}
+		})
+	}
+}
+
+func TestDownloadSegmentsFailure(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{
+			name: "server ignores ranges",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write(make([]byte, 1000))
+			},
+		},
+		{
+			// The other segments never finish on their own, so the
+			// download only returns if the failure cancels them.
+			name: "one segment fails",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Range") == "bytes=0-249" {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				w.Header().Set("Content-Range", "bytes */1000")
+				w.WriteHeader(http.StatusPartialContent)
+				w.(http.Flusher).Flush()
+				<-r.Context().Done()
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(tt.handler)
+			t.Cleanup(srv.Close)
+			p := segmentedPinnacle(t, 4)
+
+			file, err := os.Create(filepath.Join(t.TempDir(), "artifact"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = file.Close() }()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			err = p.downloadSegments(ctx, srv.URL, nil, file, 1000, nil)
+			if err == nil {
+				t.Fatal("downloadSegments() succeeded, want an error")
+			}
+			if ctx.Err() != nil {
+				t.Fatalf("downloadSegments() only returned at the test timeout: %v", err)
+			}
+		})
+	}
+}
+
+func TestDownloadLargeFile(t *testing.T) {
+	body := bytes.Repeat([]byte{'a'}, segmentThreshold)
+	hash, err := hashReader(bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrongHash := strings.Repeat("0", 40)
+
+	// plainServer serves body without advertising range support.
+	plainServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(plainServer.Close)
+
+	tests := []struct {
+		name         string
+		ranges       bool
+		segments     int
+		hash         string
+		wantErr      string
+		wantSegments int // range requests made, 0 for a regular download
+	}{
+		{name: "segmented", ranges: true, segments: 4, hash: hash, wantSegments: 4},
+		{name: "segments disabled", ranges: true, segments: 1, hash: hash},
+		{name: "no range support", segments: 4, hash: hash},
+		{name: "segmented hash mismatch", ranges: true, segments: 4, hash: wrongHash, wantErr: "hash mismatch", wantSegments: 4},
+		{name: "regular hash mismatch", segments: 4, hash: wrongHash, wantErr: "hash mismatch"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url := plainServer.URL
+			ranges := func() []string { return nil }
+			if tt.ranges {
+				var srv *httptest.Server
+				srv, ranges = rangeServer(t, body)
+				url = srv.URL
+			}
+			p := segmentedPinnacle(t, tt.segments)
+			path := p.alpinePath("artifact")
+
+			err := p.downloadLargeFile(context.Background(), url, nil, path, uint32(len(body)), tt.hash, nil)
+			checkErrContains(t, err, tt.wantErr)
+
+			requested := slices.DeleteFunc(ranges(), func(r string) bool { return r == "" })
+			if len(requested) != tt.wantSegments {
+				t.Errorf("made %d range request(s), want %d", len(requested), tt.wantSegments)
+			}
+			if err == nil {
+				if info, serr := os.Stat(path); serr != nil || info.Size() != int64(len(body)) {
+					t.Errorf("downloaded file: %v, %v", info, serr)
+				}
+			}
+		})
+	}
+}
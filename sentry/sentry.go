@@ -95,6 +95,26 @@ func (c *Client) Breadcrumb(ctx context.Context, desc string, level slog.Level)
 	}
 }
 
+// CaptureWarning reports a problem that isn't a bug but is worth
+// counting, such as a wrong system clock, at warning level with tags.
+func (c *Client) CaptureWarning(ctx context.Context, message string, tags map[string]string) *sentry.EventID {
+	if !c.enabled {
+		return nil
+	}
+	hub := sentry.GetHubFromContext(ctx)
+	if hub == nil {
+		hub = sentry.CurrentHub()
+	}
+
+	var id *sentry.EventID
+	hub.WithScope(func(scope *sentry.Scope) {
+		scope.SetLevel(sentry.LevelWarning)
+		scope.SetTags(tags)
+		id = hub.CaptureMessage(message)
+	})
+	return id
+}
+
 // CaptureErr reports an error to Sentry.
 func (c *Client) CaptureErr(ctx context.Context, err error, attachment ...string) *sentry.EventID {
 	if err == nil {
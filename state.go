@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// runState records when Pinnacle last finished an update and started
+// the launcher. It is kept in state.json and shown by the status command.
+type runState struct {
+	LastUpdate time.Time `json:"last_update,omitzero"`
+	LastLaunch time.Time `json:"last_launch,omitzero"`
+}
+
+func (p *Pinnacle) readState() runState {
+	var state runState
+	data, err := os.ReadFile(p.statePath("state.json"))
+	if err == nil {
+		_ = json.Unmarshal(data, &state)
+	}
+	return state
+}
+
+// recordState applies update to the saved state and writes it back.
+func (p *Pinnacle) recordState(update func(*runState)) error {
+	state := p.readState()
+	update(&state)
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(p.stateDir, os.ModePerm); err != nil {
+		return err
+	}
+	path := p.statePath("state.json")
+	if err = os.WriteFile(path+".tmp", data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// statusReport is what the status command knows about an installation.
+type statusReport struct {
+	DataDir    string            `json:"data_dir"`
+	CacheDir   string            `json:"cache_dir"`
+	StateDir   string            `json:"state_dir"`
+	Branch     string            `json:"branch"`
+	Launcher   *fileStatus       `json:"launcher"`
+	Pinned     string            `json:"pinned_launcher_version,omitempty"`
+	Java       []javaStatus      `json:"java"`
+	LastUpdate time.Time         `json:"last_update,omitzero"`
+	LastLaunch time.Time         `json:"last_launch,omitzero"`
+	Components []componentStatus `json:"components"`
+	Error      string            `json:"error,omitempty"`
+}
+
+type fileStatus struct {
+	Path string `json:"path"`
+	Hash string `json:"sha1"`
+	Size int64  `json:"size"`
+}
+
+type javaStatus struct {
+	Major    string        `json:"major"`
+	Version  string        `json:"version,omitempty"` // JAVA_VERSION from the runtime's release file
+	Manifest *JavaManifest `json:"manifest,omitempty"`
+}
+
+type componentStatus struct {
+	Name    string `json:"name"`
+	Current bool   `json:"current"`
+	Status  string `json:"status"` // "up-to-date", "outdated" or "unknown"
+	Reason  string `json:"reason,omitempty"`
+}
+
+// Status reports what is installed and whether each component is
+// current with the metadata server.
+func (p *Pinnacle) Status(ctx context.Context, w io.Writer, format string) error {
+	state := p.readState()
+	report := statusReport{
+		DataDir:    p.alpinePath(),
+		CacheDir:   p.cacheDir,
+		StateDir:   p.stateDir,
+		Branch:     p.branch,
+		Launcher:   p.launcherStatus(),
+		Pinned:     p.config.LauncherVersion,
+		Java:       p.javaStatus(),
+		LastUpdate: state.LastUpdate,
+		LastLaunch: state.LastLaunch,
+	}
+
+	// The built-in components can still be checked if the list of
+	// extra components can't be fetched.
+	if err := p.registerManifestComponents(ctx); err != nil {
+		report.Error = err.Error()
+	}
+	components, err := orderComponents(p.components)
+	if err != nil {
+		return err
+	}
+	for _, c := range components {
+		cp := p.planComponent(ctx, c)
+		cs := componentStatus{Name: cp.Name, Reason: cp.Reason}
+		switch cp.Status {
+		case "up-to-date":
+			cs.Current = true
+			cs.Status = "up-to-date"
+		case "install":
+			cs.Status = "outdated"
+		default:
+			cs.Status = "unknown"
+		}
+		report.Components = append(report.Components, cs)
+	}
+
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	return report.print(w)
+}
+
+func (p *Pinnacle) launcherStatus() *fileStatus {
+	path := p.launcherPath()
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil
+	}
+	hash, err := hashReader(file)
+	if err != nil {
+		return nil
+	}
+	return &fileStatus{Path: path, Hash: hash, Size: info.Size()}
+}
+
+// javaStatus lists every runtime under jre/, with its manifest if it has one.
+func (p *Pinnacle) javaStatus() []javaStatus {
+	entries, err := os.ReadDir(p.alpinePath("jre"))
+	if err != nil {
+		return nil
+	}
+
+	var runtimes []javaStatus
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		js := javaStatus{
+			Major:   entry.Name(),
+			Version: readJavaRelease(p.alpinePath("jre", entry.Name(), "extracted", "release")),
+		}
+		data, err := os.ReadFile(p.alpinePath("jre", entry.Name(), "version.json"))
+		if err == nil {
+			var manifest JavaManifest
+			if json.Unmarshal(data, &manifest) == nil {
+				js.Manifest = &manifest
+			}
+		}
+		runtimes = append(runtimes, js)
+	}
+	return runtimes
+}
+
+// readJavaRelease returns JAVA_VERSION from a runtime's release file.
+func readJavaRelease(path string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), "JAVA_VERSION="); ok {
+			return strings.Trim(value, `"`)
+		}
+	}
+	return ""
+}
+
+func (r *statusReport) print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "Data directory:\t%s\n", r.DataDir)
+	if r.CacheDir != r.DataDir {
+		_, _ = fmt.Fprintf(tw, "Cache directory:\t%s\n", r.CacheDir)
+	}
+	if r.StateDir != r.DataDir {
+		_, _ = fmt.Fprintf(tw, "State directory:\t%s\n", r.StateDir)
+	}
+	_, _ = fmt.Fprintf(tw, "Branch:\t%s\n", r.Branch)
+
+	if r.Launcher != nil {
+		_, _ = fmt.Fprintf(tw, "Launcher:\t%s, %s\n", r.Launcher.Hash, formatBytes(r.Launcher.Size))
+	} else {
+		_, _ = fmt.Fprintf(tw, "Launcher:\tnot installed\n")
+	}
+
+	if len(r.Java) == 0 {
+		_, _ = fmt.Fprintf(tw, "Java:\tnot installed\n")
+	}
+	for _, js := range r.Java {
+		detail := js.Version
+		if detail == "" {
+			detail = "unknown version"
+		}
+		if js.Manifest != nil {
+			detail += fmt.Sprintf(", archive %s, %d file(s) recorded", js.Manifest.Hash, len(js.Manifest.Files))
+		} else {
+			detail += ", no manifest"
+		}
+		_, _ = fmt.Fprintf(tw, "Java %s:\t%s\n", js.Major, detail)
+	}
+
+	if r.Pinned != "" {
+		_, _ = fmt.Fprintf(tw, "Launcher version:\tpinned to %s, updates are paused\n", r.Pinned)
+	}
+	_, _ = fmt.Fprintf(tw, "Last update:\t%s\n", formatTime(r.LastUpdate))
+	_, _ = fmt.Fprintf(tw, "Last launch:\t%s\n\n", formatTime(r.LastLaunch))
+
+	for _, c := range r.Components {
+		switch c.Status {
+		case "up-to-date":
+			_, _ = fmt.Fprintf(tw, "%s\tup to date\n", c.Name)
+		case "outdated":
+			_, _ = fmt.Fprintf(tw, "%s\tneeds update\t%s\n", c.Name, c.Reason)
+		default:
+			_, _ = fmt.Fprintf(tw, "%s\tunknown\t%s\n", c.Name, c.Reason)
+		}
+	}
+	if r.Error != "" {
+		_, _ = fmt.Fprintf(tw, "\nSome components could not be listed: %s\n", r.Error)
+	}
+	return tw.Flush()
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.Local().Format(time.DateTime)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStatus(t *testing.T) {
+	dir := t.TempDir()
+	p := testPinnacle(dir)
+	p.branch = defaultBranch
+	p.config.LauncherVersion = "1.2.3"
+	p.manifestsRegistered = true
+	p.register(
+		&fakeComponent{name: "java"},
+		&outdatedComponent{fakeComponent: fakeComponent{name: "launcher"}, meta: &MetadataResponse{}},
+		&fakeComponent{name: "mods", err: errors.New("internet failure")},
+	)
+
+	if err := os.WriteFile(p.alpinePath("launcher.jar"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(p.alpinePath("jre", "17", "extracted"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	release := "IMPLEMENTOR=\"Eclipse Adoptium\"\nJAVA_VERSION=\"17.0.9\"\n"
+	if err := os.WriteFile(p.alpinePath("jre", "17", "extracted", "release"), []byte(release), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p.alpinePath("jre", "17", "version.json"), []byte(`{"checksum":"abc","files":{"bin/java":"1"}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	updated := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	if err := p.recordState(func(s *runState) { s.LastUpdate = updated }); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := p.Status(context.Background(), &out, "json"); err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	var report statusReport
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Launcher == nil || report.Launcher.Hash != emptySHA1 || report.Launcher.Size != 0 {
+		t.Errorf("launcher = %+v, want the empty launcher.jar", report.Launcher)
+	}
+	if len(report.Java) != 1 || report.Java[0].Version != "17.0.9" ||
+		report.Java[0].Manifest == nil || report.Java[0].Manifest.Hash != "abc" {
+		t.Errorf("java = %+v, want 17.0.9 with its manifest", report.Java)
+	}
+	if !report.LastUpdate.Equal(updated) || !report.LastLaunch.IsZero() {
+		t.Errorf("last update %v and launch %v, want %v and never", report.LastUpdate, report.LastLaunch, updated)
+	}
+	if report.Pinned != "1.2.3" {
+		t.Errorf("pinned = %q, want 1.2.3", report.Pinned)
+	}
+	want := []componentStatus{
+		{Name: "java", Current: true, Status: "up-to-date"},
+		{Name: "launcher", Status: "outdated", Reason: "needs install: missing launcher"},
+		{Name: "mods", Status: "unknown", Reason: "internet failure"},
+	}
+	if len(report.Components) != len(want) {
+		t.Fatalf("components = %+v, want %+v", report.Components, want)
+	}
+	for i := range want {
+		if report.Components[i] != want[i] {
+			t.Errorf("component %d = %+v, want %+v", i, report.Components[i], want[i])
+		}
+	}
+
+	out.Reset()
+	if err := report.print(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"Java 17:           17.0.9, archive abc, 1 file(s) recorded",
+		"Launcher version:  pinned to 1.2.3, updates are paused",
+		"Last launch:       never",
+		"launcher  needs update  needs install: missing launcher",
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("text status is missing %q:\n%s", line, out.String())
+		}
+	}
+}
+
+func TestStatusNothingInstalled(t *testing.T) {
+	p := testPinnacle(t.TempDir())
+	p.manifestsRegistered = true
+
+	var out bytes.Buffer
+	if err := p.Status(context.Background(), &out, "text"); err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	for _, line := range []string{"Launcher:        not installed", "Java:            not installed", "Last update:     never"} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("text status is missing %q:\n%s", line, out.String())
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// tlsVersions are the accepted minimum versions. Go already refuses
+// anything older than 1.2, so the setting can only raise the floor.
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// untrustedCertError is returned when a server presents a certificate
+// chain that does not lead to a trusted root, which usually means the
+// connection is being intercepted by a corporate or school proxy.
+type untrustedCertError struct {
+	host   string
+	issuer string
+}
+
+func (e *untrustedCertError) Error() string {
+	return fmt.Sprintf("The secure connection to %s was blocked by a certificate issued by %q, "+
+		"which this computer does not trust.\n\n"+
+		"If you are on a school or work network, ask your administrator for their CA certificate "+
+		"and add it with -ca-file or \"ca_files\" in config.json.", e.host, e.issuer)
+}
+
+// configureTLS applies extra CA certificates and a minimum TLS version
+// to the transport shared by every request Pinnacle makes.
+func configureTLS(caFiles []string, minVersion string) error {
+	transport, ok := httpClient.Transport.(*http.Transport)
+	if !ok {
+		return errors.New("unexpected http transport")
+	}
+
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if minVersion != "" {
+		v, found := tlsVersions[strings.TrimPrefix(minVersion, "TLS")]
+		if !found {
+			return fmt.Errorf("unsupported TLS version %q", minVersion)
+		}
+		cfg.MinVersion = v
+	}
+	// Apply the minimum version even if a CA file turns out to be unusable.
+	transport.TLSClientConfig = cfg
+
+	if len(caFiles) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		for _, path := range caFiles {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("unable to read CA file: %w", err)
+			}
+			if !pool.AppendCertsFromPEM(data) {
+				return fmt.Errorf("no PEM certificates found in %s", path)
+			}
+		}
+		cfg.RootCAs = pool
+	}
+	return nil
+}
+
+// certificateError explains certificate verification failures: a wrong
+// system clock takes precedence, then untrusted issuers. It returns nil
+// for errors that are not certificate failures.
+func (p *Pinnacle) certificateError(ctx context.Context, host string, err error) error {
+	var verifyErr *tls.CertificateVerificationError
+	if !errors.As(err, &verifyErr) {
+		return nil
+	}
+	if skewErr := p.checkClockSkew(ctx, host); skewErr != nil {
+		return skewErr
+	}
+	return asUntrustedCertError(host, err)
+}
+
+// asUntrustedCertError converts certificate authority failures into
+// an untrustedCertError naming the issuer. Other errors return nil.
+func asUntrustedCertError(host string, err error) error {
+	var authErr x509.UnknownAuthorityError
+	if !errors.As(err, &authErr) {
+		return nil
+	}
+
+	issuer := "an unknown issuer"
+	if authErr.Cert != nil {
+		switch {
+		case authErr.Cert.Issuer.CommonName != "":
+			issuer = authErr.Cert.Issuer.CommonName
+		case len(authErr.Cert.Issuer.Organization) > 0:
+			issuer = authErr.Cert.Issuer.Organization[0]
+		}
+	}
+	return &untrustedCertError{host: host, issuer: issuer}
+}
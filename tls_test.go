@@ -0,0 +1,144 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestCA writes a self-signed PEM certificate to dir and returns its path.
+func writeTestCA(t *testing.T, dir string) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "School Proxy CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "ca.pem")
+	if err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConfigureTLS(t *testing.T) {
+	transport := httpClient.Transport.(*http.Transport)
+	saved := transport.TLSClientConfig
+	t.Cleanup(func() { transport.TLSClientConfig = saved })
+
+	dir := t.TempDir()
+	ca := writeTestCA(t, dir)
+	notPEM := filepath.Join(dir, "ca.txt")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		caFiles    []string
+		minVersion string
+		wantMin    uint16
+		wantPool   bool
+		wantErr    string
+	}{
+		{name: "defaults", wantMin: tls.VersionTLS12},
+		{name: "tls 1.3", minVersion: "1.3", wantMin: tls.VersionTLS13},
+		{name: "tls prefix", minVersion: "TLS1.3", wantMin: tls.VersionTLS13},
+		{name: "lowering the floor", minVersion: "1.1", wantErr: `unsupported TLS version "1.1"`},
+		{name: "ca file", caFiles: []string{ca}, wantMin: tls.VersionTLS12, wantPool: true},
+		{name: "missing ca file", caFiles: []string{filepath.Join(dir, "missing.pem")}, wantMin: tls.VersionTLS12, wantErr: "unable to read CA file"},
+		{name: "not pem", caFiles: []string{ca, notPEM}, minVersion: "1.3", wantMin: tls.VersionTLS13, wantErr: "no PEM certificates found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport.TLSClientConfig = nil
+
+			err := configureTLS(tt.caFiles, tt.minVersion)
+			checkErrContains(t, err, tt.wantErr)
+
+			cfg := transport.TLSClientConfig
+			if tt.wantMin == 0 {
+				if cfg != nil {
+					t.Errorf("TLS config applied despite an invalid version: %+v", cfg)
+				}
+				return
+			}
+			if cfg == nil || cfg.MinVersion != tt.wantMin {
+				t.Fatalf("TLS config = %+v, want minimum version %x", cfg, tt.wantMin)
+			}
+			if (cfg.RootCAs != nil) != tt.wantPool {
+				t.Errorf("custom root CAs = %t, want %t", cfg.RootCAs != nil, tt.wantPool)
+			}
+		})
+	}
+}
+
+func TestAsUntrustedCertError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantIssuer string
+	}{
+		{
+			name:       "common name",
+			err:        x509.UnknownAuthorityError{Cert: &x509.Certificate{Issuer: pkix.Name{CommonName: "Fortinet CA", Organization: []string{"Fortinet"}}}},
+			wantIssuer: "Fortinet CA",
+		},
+		{
+			name:       "organization",
+			err:        x509.UnknownAuthorityError{Cert: &x509.Certificate{Issuer: pkix.Name{Organization: []string{"Contoso School"}}}},
+			wantIssuer: "Contoso School",
+		},
+		{name: "no certificate", err: x509.UnknownAuthorityError{}, wantIssuer: "an unknown issuer"},
+		{
+			name:       "wrapped",
+			err:        fmt.Errorf("tls: %w", &tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}),
+			wantIssuer: "an unknown issuer",
+		},
+		{name: "expired certificate", err: x509.CertificateInvalidError{Reason: x509.Expired}},
+		{name: "other", err: errors.New("connection reset")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := asUntrustedCertError("metadata.alpineclient.com", tt.err)
+
+			var certErr *untrustedCertError
+			if !errors.As(err, &certErr) {
+				if tt.wantIssuer != "" {
+					t.Fatalf("asUntrustedCertError() = %v, want an untrusted certificate", err)
+				}
+				return
+			}
+			if tt.wantIssuer == "" {
+				t.Fatalf("asUntrustedCertError() = %v, want nil", err)
+			}
+			if certErr.issuer != tt.wantIssuer || certErr.host != "metadata.alpineclient.com" {
+				t.Errorf("asUntrustedCertError() = %+v, want issuer %q", certErr, tt.wantIssuer)
+			}
+		})
+	}
+}
@@ -15,11 +15,53 @@
 
 // DisplayError closes the progress-bar, sends the error to sentry and displays a pop-up for the user
 // Also adds a breadcrumb to the provided sentry hub connected to the context.
-func DisplayError(ctx context.Context, err error, logFile *os.File, sentryClient *sentry.Client) error {
+// It reports whether the user asked to repair the installation.
+func DisplayError(ctx context.Context, err error, logFile *os.File, sentryClient *sentry.Client) (bool, error) {
 	if err == nil {
-		return nil
+		return false, nil
 	}
 
+	message := reportError(ctx, err, logFile, sentryClient)
+
+	choice := zenity.Question(
+		message+"\n\nTry repairing your installation, or join our Discord for help.",
+		zenity.Title("Error"),
+		zenity.OKLabel("Repair"),
+		zenity.CancelLabel("Close"),
+		zenity.ExtraButton("Help (Discord)"),
+		zenity.ErrorIcon,
+	)
+
+	switch {
+	case choice == nil:
+		return true, nil
+	case errors.Is(choice, zenity.ErrExtraButton):
+		return false, openSupportWebsite(ctx)
+	}
+
+	return false, nil
+}
+
+// DisplayRepairFailed is like DisplayError for a repair that failed
+// itself, so it offers help instead of another repair.
+func DisplayRepairFailed(ctx context.Context, err error, sentryClient *sentry.Client) error {
+	message := reportError(ctx, err, nil, sentryClient)
+
+	choice := zenity.Error(
+		"Repairing your installation failed:\n\n"+message+"\n\nJoin our Discord for help.",
+		zenity.Title("Repair Failed"),
+		zenity.OKLabel("Close"),
+		zenity.ExtraButton("Help (Discord)"),
+	)
+	if errors.Is(choice, zenity.ErrExtraButton) {
+		return openSupportWebsite(ctx)
+	}
+	return nil
+}
+
+// reportError closes the progress-bar, sends err with the contents of
+// logFile to sentry and returns the message to show, including the event id.
+func reportError(ctx context.Context, err error, logFile *os.File, sentryClient *sentry.Client) string {
 	Close() // close progress bar
 
 	message := err.Error()
@@ -46,35 +88,77 @@ func DisplayError(ctx context.Context, err error, logFile *os.File, sentryClient
 
 	sentry.Flush(2 * time.Second)
 
-	choice := zenity.Error(
-		message+"\n\nJoin our Discord for help.",
+	return message
+}
+
+// DisplayRepairSummary shows what a repair changed.
+func DisplayRepairSummary(summary string) {
+	Close() // close progress bar
+
+	_ = zenity.Info(
+		summary,
+		zenity.Title("Repair Complete"),
+		zenity.InfoIcon,
+	)
+}
+
+// DisplayConfigError tells the user their settings are invalid
+// and how to fix them. Nothing is reported to Sentry.
+func DisplayConfigError(err error) {
+	_ = zenity.Error(
+		err.Error()+"\n\nFix the settings above, or run \"pinnacle config list\" to see every setting.",
+		zenity.Title("Invalid Settings"),
+		zenity.ErrorIcon,
+	)
+}
+
+// DisplayActionRequired tells the user about a problem only they can
+// fix, such as an expired access token or a wrong system clock.
+// Nothing is reported to Sentry as an error.
+func DisplayActionRequired(err error) {
+	Close() // close progress bar
+
+	_ = zenity.Error(
+		err.Error(),
+		zenity.Title("Action Required"),
+		zenity.WarningIcon,
+	)
+}
+
+// DisplaySetupError tells the user Pinnacle couldn't start, before
+// logging or Sentry are available.
+func DisplaySetupError(err error) {
+	_ = zenity.Error(
+		"Alpine Client couldn't start:\n\n"+err.Error()+"\n\nJoin our Discord for help.",
 		zenity.Title("Error"),
-		zenity.OKLabel("Close"),
-		zenity.ExtraButton("Help (Discord)"),
 		zenity.ErrorIcon,
 	)
+}
 
-	if errors.Is(choice, zenity.ErrExtraButton) {
-		return openSupportWebsite(ctx)
-	}
+// DisplayLoginPage tells the user their network requires signing in
+// through a web page and offers to open it in the browser.
+func DisplayLoginPage(ctx context.Context, pageURL string) error {
+	Close() // close progress bar
 
-	return nil
+	err := zenity.Question(
+		"You appear to be behind a login page, for example on hotel, school or public Wi-Fi.\n\n"+
+			"Sign in through your browser, then start Alpine Client again.",
+		zenity.Title("No Internet Access"),
+		zenity.OKLabel("Open Browser"),
+		zenity.CancelLabel("Close"),
+		zenity.WarningIcon,
+	)
+	if err != nil {
+		return nil //nolint:nilerr // cancel means the user closed the dialog
+	}
+	return openURL(ctx, pageURL)
 }
 
-// openSupportWebsite tries to open the specified URL in the default browser.
+// openSupportWebsite tries to open the support server in the default browser.
 func openSupportWebsite(ctx context.Context) error {
 	const supportURL string = "https://discord.alpineclient.com"
-	var err error
-
-	switch runtime.GOOS {
-	case "windows":
-		err = exec.CommandContext(ctx, "rundll32", "url.dll,FileProtocolHandler", supportURL).Run()
-	case "linux":
-		err = exec.CommandContext(ctx, "xdg-open", supportURL).Run()
-	case "darwin":
-		err = exec.CommandContext(ctx, "open", supportURL).Run()
-	}
 
+	err := openURL(ctx, supportURL)
 	if err != nil {
 		// None of the above worked. Create new popup with url.
 		_ = zenity.Info(
@@ -86,3 +170,16 @@ func openSupportWebsite(ctx context.Context) error {
 	}
 	return nil
 }
+
+// openURL opens the specified URL in the default browser.
+func openURL(ctx context.Context, url string) error {
+	switch runtime.GOOS {
+	case "windows":
+		return exec.CommandContext(ctx, "rundll32", "url.dll,FileProtocolHandler", url).Run()
+	case "linux":
+		return exec.CommandContext(ctx, "xdg-open", url).Run()
+	case "darwin":
+		return exec.CommandContext(ctx, "open", url).Run()
+	}
+	return errors.New("unsupported operating system: " + runtime.GOOS)
+}
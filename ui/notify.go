@@ -1,6 +1,7 @@
 package ui
 
 import (
+	"context"
 	"log/slog"
 
 	"github.com/ncruces/zenity"
@@ -16,3 +17,35 @@ func NotifyNewUpdate(l *slog.Logger) {
 	l.Info(msg)
 	_ = zenity.Notify(msg, zenity.Title(WindowTitle))
 }
+
+// NotifyUpdatesPaused reminds the user the launcher is pinned to a version.
+func NotifyUpdatesPaused(l *slog.Logger, version string) {
+	msg := "Launcher updates are paused at version " + version +
+		".\n\nRun \"pinnacle config unset launcher_version\" to resume them."
+
+	l.Info(msg)
+	_ = zenity.Notify(msg, zenity.Title(WindowTitle))
+}
+
+// DisplayUpdateRequired shows a blocking dialog telling the user this
+// version of Pinnacle can no longer be used, and offers the download page.
+func DisplayUpdateRequired(ctx context.Context, required string) error {
+	Close() // close progress bar
+
+	msg := "This version of Alpine Client's installer is no longer supported."
+	if required != "" {
+		msg += "\n\nVersion " + required + " or newer is required."
+	}
+
+	err := zenity.Question(
+		msg+"\n\nPlease download the latest version from "+downloadURL,
+		zenity.Title("Update Required"),
+		zenity.OKLabel("Download"),
+		zenity.CancelLabel("Close"),
+		zenity.WarningIcon,
+	)
+	if err != nil {
+		return nil //nolint:nilerr // cancel means the user closed the dialog
+	}
+	return openURL(ctx, downloadURL)
+}
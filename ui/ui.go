@@ -4,6 +4,7 @@
 	"fmt"
 	"log/slog"
 	"math"
+	"sync"
 
 	"github.com/ncruces/zenity"
 )
@@ -15,32 +16,39 @@
 	MaxProgress  int    = 1000 // 1000 is used over 100 to make bar smoother
 )
 
+// mu guards the dialog and tasks, which are updated
+// from concurrently installing components.
 var (
+	mu     sync.Mutex
 	dialog zenity.ProgressDialog
 	tasks  []*ProgressiveTask
 )
 
 type ProgressiveTask struct {
 	label    string
+	note     string
 	progress int
 }
 
+// NewProgressTask adds a task to the progress dialog. Tasks can run at
+// the same time, the dialog then shows how far along they are together.
+// Call Done once the task is finished.
 func NewProgressTask(label string) *ProgressiveTask {
 	pt := &ProgressiveTask{
 		label: label,
 	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
 	tasks = append(tasks, pt)
-	if dialog != nil {
-		_ = dialog.Text(label)
-		_ = dialog.Value(0)
-	}
+	render()
 	return pt
 }
 
 func (pt *ProgressiveTask) UpdateProgress(v float64, label ...string) {
-	if dialog == nil {
-		return
-	}
+	mu.Lock()
+	defer mu.Unlock()
 
 	if len(label) > 0 {
 		pt.label = label[0]
@@ -50,12 +58,70 @@ func (pt *ProgressiveTask) UpdateProgress(v float64, label ...string) {
 	if progress < MaxProgress {
 		pt.progress = progress
 	}
+	render()
+}
 
-	_ = dialog.Text(fmt.Sprintf("%s %d%%", pt.label, pt.progress/10))
-	_ = dialog.Value(pt.progress)
+// Done removes the task from the dialog, leaving it to the other tasks.
+func (pt *ProgressiveTask) Done() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for i, t := range tasks {
+		if t == pt {
+			tasks = append(tasks[:i], tasks[i+1:]...)
+			break
+		}
+	}
+	render()
+}
+
+// render shows the mean progress of the unfinished tasks with the label
+// of the oldest one, or of the oldest one with a note such as download
+// speed, so concurrent tasks don't take turns on the dialog. mu must be
+// held.
+func render() {
+	if dialog == nil || len(tasks) == 0 {
+		return
+	}
+
+	total := 0
+	for _, t := range tasks {
+		total += t.progress
+	}
+	progress := total / len(tasks)
+
+	shown := tasks[0]
+	for _, t := range tasks {
+		if t.note != "" {
+			shown = t
+			break
+		}
+	}
+	text := shown.label
+	if len(tasks) > 1 {
+		text += fmt.Sprintf(" (+%d more)", len(tasks)-1)
+	}
+	text += fmt.Sprintf(" %d%%", progress/10)
+	if shown.note != "" {
+		text += " (" + shown.note + ")"
+	}
+	_ = dialog.Text(text)
+	_ = dialog.Value(progress)
+}
+
+// SetNote sets extra detail shown in parentheses after the
+// progress percentage, e.g. a bandwidth limit. An empty note clears it.
+func (pt *ProgressiveTask) SetNote(note string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	pt.note = note
 }
 
 func Render(l *slog.Logger) {
+	mu.Lock()
+	defer mu.Unlock()
+
 	if dialog != nil {
 		return
 	}
@@ -74,7 +140,11 @@ func Render(l *slog.Logger) {
 }
 
 func Close() {
+	mu.Lock()
+	defer mu.Unlock()
+
 	if dialog != nil {
 		_ = dialog.Close()
+		dialog = nil
 	}
 }
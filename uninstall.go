@@ -0,0 +1,234 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Uninstall removes what Pinnacle put in the data directory after
+// confirmation. With -keep-logs the logs directory is left alone, and
+// with -keep-user-data only files Pinnacle itself installed are removed,
+// leaving anything the launcher wrote.
+//
+// Pinnacle doesn't install desktop entries or shortcuts; those come from
+// the system package and are removed along with it.
+func (p *Pinnacle) Uninstall(in io.Reader, out io.Writer) error {
+	dir := p.alpinePath()
+	targets, err := p.uninstallTargets()
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		_, _ = fmt.Fprintln(out, "Nothing to remove in", dir)
+		return nil
+	}
+
+	var total int64
+	for _, path := range targets {
+		total += diskUsage(path)
+	}
+
+	if !p.opts.yes {
+		_, _ = fmt.Fprintf(out, "The following will be removed from %s:\n", dir)
+		for _, path := range targets {
+			if rel, err := filepath.Rel(dir, path); err == nil && filepath.IsLocal(rel) {
+				path = rel
+			}
+			_, _ = fmt.Fprintf(out, "  %s\n", path)
+		}
+		if !confirm(in, out, fmt.Sprintf("Remove %s?", formatBytes(total))) {
+			return errors.New("uninstall canceled")
+		}
+	}
+
+	roots := slices.Compact([]string{p.dataDir, p.cacheDir, p.stateDir})
+	var freed int64
+	for _, path := range targets {
+		size := diskUsage(path)
+		if err = os.RemoveAll(path); err != nil {
+			return err
+		}
+		freed += size
+		for _, root := range roots {
+			pruneEmptyDirs(filepath.Dir(path), root)
+		}
+	}
+	for _, root := range roots {
+		_ = os.Remove(root) // only succeeds once nothing is left
+	}
+
+	_, _ = fmt.Fprintf(out, "Removed %d item(s), freed %s.\n", len(targets), formatBytes(freed))
+	if fileExists(dir) {
+		_, _ = fmt.Fprintln(out, "Kept", dir, "because it still contains files Pinnacle didn't remove.")
+	}
+	_, _ = fmt.Fprintln(out, "Desktop shortcuts come from your package manager and are removed when you uninstall the package.")
+	return nil
+}
+
+// uninstallTargets lists the paths to remove, honoring -keep-logs and
+// -keep-user-data. Pinnacle's own files are listed by name. Without
+// -keep-user-data the rest of the data directory, which the launcher
+// owns, goes too, but only if the directory looks like Pinnacle's.
+func (p *Pinnacle) uninstallTargets() ([]string, error) {
+	targets := []string{
+		p.alpinePath("config.json"),
+		p.alpinePath("credentials.json"),
+		p.alpinePath(migratedMarker),
+		p.statePath("state.json"),
+		p.statePath("state.json.tmp"),
+	}
+	switch {
+	case p.opts.keepLogs:
+	case p.opts.keepUserData:
+		targets = append(targets, p.statePath("logs", "updater.log"))
+	default:
+		targets = append(targets, p.statePath("logs"))
+	}
+	if p.cacheDir != p.dataDir {
+		targets = append(targets, p.cacheDir) // only holds downloads
+	}
+	for _, c := range p.components {
+		targets = append(targets, c.Paths()...)
+	}
+	jars, _ := filepath.Glob(p.alpinePath("launcher-*.jar")) // other branches
+	targets = append(targets, jars...)
+	if p.legacyDir != "" && isSymlink(p.legacyDir) {
+		targets = append(targets, p.legacyDir)
+	}
+
+	installed, err := p.installedManifestFiles()
+	if err != nil {
+		return nil, err
+	}
+	targets = append(targets, installed...)
+
+	if !p.opts.keepUserData {
+		launcherData, err := p.launcherDataEntries()
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, launcherData...)
+	}
+
+	targets = slices.DeleteFunc(targets, func(path string) bool {
+		return !fileExists(path) && !isSymlink(path)
+	})
+	slices.Sort(targets)
+	targets = slices.Compact(targets)
+
+	// Removing a directory removes its contents, so don't list them too.
+	all := slices.Clone(targets)
+	return slices.DeleteFunc(targets, func(path string) bool {
+		return slices.ContainsFunc(all, func(dir string) bool {
+			return strings.HasPrefix(path, dir+string(filepath.Separator))
+		})
+	}), nil
+}
+
+// installedManifestFiles lists the files recorded as installed by
+// metadata-described components, found without asking the server, along
+// with the records themselves.
+func (p *Pinnacle) installedManifestFiles() ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(p.alpinePath(), func(path string, d fs.DirEntry, err error) error {
+		if path == p.alpinePath() && errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil || d.IsDir() || d.Name() != installedManifestName {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		installed := make(map[string]string)
+		if err = json.Unmarshal(data, &installed); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		for rel := range installed {
+			if !filepath.IsLocal(filepath.FromSlash(rel)) {
+				continue // never delete outside the component
+			}
+			files = append(files, filepath.Join(filepath.Dir(path), filepath.FromSlash(rel)))
+		}
+		files = append(files, path)
+		return nil
+	})
+	return files, err
+}
+
+// launcherDataEntries lists every entry of the data directory. It fails
+// if the directory has entries but no sign of a Pinnacle install, so a
+// mistyped -data-dir can't empty an unrelated directory.
+func (p *Pinnacle) launcherDataEntries() ([]string, error) {
+	entries, err := os.ReadDir(p.alpinePath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) > 0 && !p.looksLikeDataDir() {
+		return nil, fmt.Errorf("%s doesn't look like an Alpine Client data directory, "+
+			"refusing to remove everything in it (use -keep-user-data to remove only Pinnacle's files)", p.alpinePath())
+	}
+
+	paths := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		path := p.alpinePath(entry.Name())
+		if p.opts.keepLogs && path == p.statePath("logs") {
+			continue
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// looksLikeDataDir reports whether the data directory holds a Java
+// runtime or launcher installed by Pinnacle.
+func (p *Pinnacle) looksLikeDataDir() bool {
+	if fileExists(p.alpinePath("jre", "17", "version.json")) || fileExists(p.alpinePath("launcher.jar")) ||
+		fileExists(p.alpinePath(migratedMarker)) {
+		return true
+	}
+	jars, _ := filepath.Glob(p.alpinePath("launcher-*.jar"))
+	return len(jars) > 0
+}
+
+// diskUsage returns the total size of the files at or under path.
+func diskUsage(path string) int64 {
+	var total int64
+	_ = filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil //nolint:nilerr // unreadable entries are skipped rather than failing the total
+		}
+		if info, err := d.Info(); err == nil && !d.IsDir() {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total
+}
+
+// pruneEmptyDirs removes dir and its parents while they are empty,
+// stopping at root.
+func pruneEmptyDirs(dir, root string) {
+	for strings.HasPrefix(dir, root+string(filepath.Separator)) {
+		if os.Remove(dir) != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+func isSymlink(path string) bool {
+	info, err := os.Lstat(path)
+	return err == nil && info.Mode()&os.ModeSymlink != 0
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestUninstallTargets(t *testing.T) {
+	pinnacleFiles := []string{
+		"jre/17/version.json",
+		"jre/17/extracted/bin/java",
+		"launcher.jar",
+		"launcher-beta.jar",
+		"config.json",
+		"credentials.json",
+		"logs/updater.log",
+		"mods/.pinnacle-manifest.json",
+		"mods/alpine.jar",
+	}
+	manifest := `{"alpine.jar":"1","../saves/world.dat":"2","/etc/passwd":"3"}`
+
+	tests := []struct {
+		name         string
+		files        []string
+		keepLogs     bool
+		keepUserData bool
+		want         []string
+		wantErr      string
+	}{
+		{name: "nothing installed", want: []string{}},
+		{
+			name:  "everything",
+			files: append(slices.Clone(pinnacleFiles), "saves/world.dat", "options.txt"),
+			want: []string{
+				"config.json", "credentials.json", "jre", "launcher-beta.jar", "launcher.jar",
+				"logs", "mods", "options.txt", "saves",
+			},
+		},
+		{
+			name:     "keep logs",
+			files:    append(slices.Clone(pinnacleFiles), "logs/launcher.log"),
+			keepLogs: true,
+			want: []string{
+				"config.json", "credentials.json", "jre", "launcher-beta.jar", "launcher.jar", "mods",
+			},
+		},
+		{
+			name:         "keep user data",
+			files:        append(slices.Clone(pinnacleFiles), "saves/world.dat", "mods/user.jar", "logs/launcher.log"),
+			keepUserData: true,
+			want: []string{
+				"config.json", "credentials.json", "jre/17", "launcher-beta.jar", "launcher.jar",
+				"logs/updater.log", "mods/.pinnacle-manifest.json", "mods/alpine.jar",
+			},
+		},
+		{
+			name:         "keep user data and logs",
+			files:        pinnacleFiles,
+			keepLogs:     true,
+			keepUserData: true,
+			want: []string{
+				"config.json", "credentials.json", "jre/17", "launcher-beta.jar", "launcher.jar",
+				"mods/.pinnacle-manifest.json", "mods/alpine.jar",
+			},
+		},
+		{
+			name:    "unrelated directory",
+			files:   []string{"Documents/taxes.pdf", ".bashrc", "config.json"},
+			wantErr: "doesn't look like an Alpine Client data directory",
+		},
+		{
+			name:         "unrelated directory keeping user data",
+			files:        []string{"Documents/taxes.pdf", ".bashrc", "config.json"},
+			keepUserData: true,
+			want:         []string{"config.json"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "alpineclient")
+			for _, name := range tt.files {
+				content := ""
+				if filepath.Base(name) == installedManifestName {
+					content = manifest
+				}
+				writeTestFile(t, filepath.Join(dir, name), content)
+			}
+
+			p := &Pinnacle{dataDir: dir, cacheDir: dir, stateDir: dir}
+			p.opts.keepLogs, p.opts.keepUserData = tt.keepLogs, tt.keepUserData
+			p.register(&javaComponent{p: p}, &launcherComponent{p: p})
+
+			targets, err := p.uninstallTargets()
+			checkErrContains(t, err, tt.wantErr)
+			if err != nil {
+				return
+			}
+
+			got := make([]string, 0, len(targets))
+			for _, path := range targets {
+				rel, err := filepath.Rel(dir, path)
+				if err != nil || !filepath.IsLocal(rel) {
+					t.Fatalf("target %s is outside the data directory", path)
+				}
+				got = append(got, filepath.ToSlash(rel))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("uninstallTargets() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUninstallTargetsSeparateDirs(t *testing.T) {
+	root := t.TempDir()
+	p := &Pinnacle{
+		dataDir:  filepath.Join(root, "data"),
+		cacheDir: filepath.Join(root, "cache"),
+		stateDir: filepath.Join(root, "state"),
+	}
+	writeTestFile(t, p.alpinePath("launcher.jar"), "")
+	writeTestFile(t, p.cachePath("jre", "17", "jre.zip"), "")
+	writeTestFile(t, p.statePath("state.json"), "{}")
+	writeTestFile(t, p.statePath("logs", "updater.log"), "")
+	writeTestFile(t, filepath.Join(root, "state", "unrelated"), "")
+	p.register(&launcherComponent{p: p})
+
+	targets, err := p.uninstallTargets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		p.cacheDir,
+		p.alpinePath("launcher.jar"),
+		p.statePath("logs"),
+		p.statePath("state.json"),
+	}
+	if !slices.Equal(targets, want) {
+		t.Errorf("uninstallTargets() = %q, want %q", targets, want)
+	}
+}
+
+func writeTestFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,7 +1,15 @@
 package main
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 )
 
 func (sys OperatingSystem) javaExecutable() string {
@@ -15,3 +23,81 @@ func fileExists(path string) bool {
 	_, err := os.Stat(path)
 	return err == nil
 }
+
+// formatBytes renders n as a human-readable size, e.g. "1.5 MB".
+func formatBytes(n int64) string {
+	const unit = 1000
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "kMGTPE"[exp])
+}
+
+// parseByteSize parses sizes such as "500000", "500K", "2MB" or "1.5m".
+// Suffixes are decimal (K = 1000).
+func parseByteSize(input string) (int64, error) {
+	s := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(input)), "B")
+	if s == "" {
+		return 0, nil
+	}
+
+	multiplier := 1.0
+	switch s[len(s)-1] {
+	case 'K':
+		multiplier = 1e3
+	case 'M':
+		multiplier = 1e6
+	case 'G':
+		multiplier = 1e9
+	}
+	if multiplier > 1 {
+		s = s[:len(s)-1]
+	}
+
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid size %q", input)
+	}
+	return int64(v * multiplier), nil
+}
+
+// hashReader returns the hex encoded sha1 of everything read from r.
+func hashReader(r io.Reader) (string, error) {
+	sha := sha1.New()
+	if _, err := io.Copy(sha, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sha.Sum(nil)), nil
+}
+
+// hashTree returns the sha1 of every regular file below root,
+// keyed by slash-separated path relative to root.
+func hashTree(root string) (map[string]string, error) {
+	hashes := make(map[string]string)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = file.Close()
+		}()
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		hashes[filepath.ToSlash(rel)], err = hashReader(file)
+		return err
+	})
+	return hashes, err
+}
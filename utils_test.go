@@ -0,0 +1,37 @@
+package main
+
+import "testing"
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    int64
+		wantErr bool
+	}{
+		{input: "", want: 0},
+		{input: "  ", want: 0},
+		{input: "512", want: 512},
+		{input: "512B", want: 512},
+		{input: "2K", want: 2_000},
+		{input: "2kb", want: 2_000},
+		{input: "1.5M", want: 1_500_000},
+		{input: " 10MB ", want: 10_000_000},
+		{input: "1G", want: 1_000_000_000},
+		{input: "0", want: 0},
+		{input: "-1M", wantErr: true},
+		{input: "M", wantErr: true},
+		{input: "fast", wantErr: true},
+		{input: "1T", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseByteSize(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseByteSize(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseByteSize(%q) = %d, want %d", tt.input, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// migratedMarker is written into a copied data directory before it is
+// moved into place, so an interrupted migration can tell a finished
+// copy apart from data that was already there.
+const migratedMarker = ".migrated-from-legacy"
+
+// resolveXDGDirs sets the Linux directories from XDG_DATA_HOME,
+// XDG_CACHE_HOME and XDG_STATE_HOME, remembering ~/.alpineclient
+// for migration and as a compatibility link for the launcher.
+func (p *Pinnacle) resolveXDGDirs() error {
+	home := os.Getenv("HOME")
+	if home == "" || !filepath.IsAbs(home) {
+		return errors.New("unable to find a data directory: HOME is not set to an absolute path, " +
+			"use -data-dir or PINNACLE_DATA_DIR")
+	}
+
+	xdg := func(variable string, fallback ...string) string {
+		// The spec says relative paths are invalid and should be ignored.
+		if dir := os.Getenv(variable); filepath.IsAbs(dir) {
+			return filepath.Join(dir, "alpineclient")
+		}
+		return filepath.Join(append(append([]string{home}, fallback...), "alpineclient")...)
+	}
+
+	p.dataDir = xdg("XDG_DATA_HOME", ".local", "share")
+	p.cacheDir = xdg("XDG_CACHE_HOME", ".cache")
+	p.stateDir = xdg("XDG_STATE_HOME", ".local", "state")
+	p.legacyDir = filepath.Join(home, ".alpineclient")
+	return nil
+}
+
+// migrateLegacyDir moves ~/.alpineclient to the XDG data directory and
+// leaves a symlink in its place, so the launcher still finds its files
+// there. Every step can be repeated, so a run that was interrupted
+// finishes the migration next time. With readOnly nothing is changed,
+// and a directory that hasn't been migrated yet is used as it is.
+//
+// If the migration fails, the legacy directory keeps being used.
+func (p *Pinnacle) migrateLegacyDir(readOnly bool) error {
+	if p.legacyDir == "" {
+		return nil
+	}
+
+	info, err := os.Lstat(p.legacyDir)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		// Create the symlink up front on a fresh install too, otherwise the
+		// launcher creates a real ~/.alpineclient and the next run finds both.
+		if !readOnly {
+			if err = os.MkdirAll(p.dataDir, os.ModePerm); err != nil {
+				return err
+			}
+			if err = os.Symlink(p.dataDir, p.legacyDir); err != nil {
+				return err
+			}
+		}
+	case err != nil:
+		return err
+	case info.Mode()&os.ModeSymlink != 0:
+		// Already migrated, or pointed elsewhere by the user, in which case
+		// the launcher keeps using the link's target and so must Pinnacle.
+		target, same, lerr := p.legacyLinkTarget()
+		if lerr != nil {
+			return lerr
+		}
+		if !same {
+			p.useLegacyDir(target)
+			return nil
+		}
+	case !info.IsDir():
+		return fmt.Errorf("%s is not a directory", p.legacyDir)
+	case readOnly:
+		p.useLegacyDir(p.legacyDir)
+		return nil
+	default:
+		legacy, data := p.legacyDir, p.dataDir
+		if err = p.moveLegacyDir(); err != nil {
+			p.useLegacyDir(p.legacyDir)
+			return fmt.Errorf("unable to move %s to %s: %w", legacy, data, err)
+		}
+	}
+	if readOnly {
+		return nil
+	}
+
+	// Logs and run history belong in the state directory.
+	for _, name := range []string{"logs", "state.json"} {
+		if !fileExists(p.alpinePath(name)) || fileExists(p.statePath(name)) {
+			continue
+		}
+		if err = os.MkdirAll(p.stateDir, os.ModePerm); err != nil {
+			return err
+		}
+		if err = moveTree(p.alpinePath(name), p.statePath(name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// useLegacyDir keeps every file in dir, ~/.alpineclient or wherever it
+// links to, as before XDG support.
+func (p *Pinnacle) useLegacyDir(dir string) {
+	p.dataDir, p.cacheDir, p.stateDir = dir, dir, dir
+	p.legacyDir = ""
+}
+
+// legacyLinkTarget resolves the ~/.alpineclient symlink and reports
+// whether it points to the data directory.
+func (p *Pinnacle) legacyLinkTarget() (string, bool, error) {
+	if link, err := os.Readlink(p.legacyDir); err == nil && link == p.dataDir {
+		return p.dataDir, true, nil // the link made by migrateLegacyDir
+	}
+
+	target, err := filepath.EvalSymlinks(p.legacyDir)
+	if err != nil {
+		return "", false, fmt.Errorf("unable to follow %s: %w", p.legacyDir, err)
+	}
+	if data, err := filepath.EvalSymlinks(p.dataDir); err == nil && data == target {
+		return p.dataDir, true, nil
+	}
+	return target, false, nil
+}
+
+// moveLegacyDir moves the legacy directory into place with a rename, or
+// by copying when the data directory is on another file system. Only
+// then is the legacy directory replaced by a symlink.
+func (p *Pinnacle) moveLegacyDir() error {
+	if err := os.MkdirAll(filepath.Dir(p.dataDir), os.ModePerm); err != nil {
+		return err
+	}
+
+	if !fileExists(p.dataDir) {
+		// A copy interrupted by an earlier run is started over.
+		partial := p.dataDir + ".migrating"
+		if err := os.RemoveAll(partial); err != nil {
+			return err
+		}
+		if err := os.Rename(p.legacyDir, p.dataDir); err != nil {
+			// Usually a different file system, copy to a temporary directory
+			// and only move it into place once it is complete.
+			if err = copyTree(p.legacyDir, partial); err != nil {
+				return err
+			}
+			if err = os.WriteFile(filepath.Join(partial, migratedMarker), nil, 0o600); err != nil {
+				return err
+			}
+			if err = os.Rename(partial, p.dataDir); err != nil {
+				return err
+			}
+		}
+	}
+
+	if fileExists(p.legacyDir) {
+		if !fileExists(p.alpinePath(migratedMarker)) {
+			return fmt.Errorf("both %s and %s exist", p.legacyDir, p.dataDir)
+		}
+		if err := os.RemoveAll(p.legacyDir); err != nil {
+			return err
+		}
+	}
+	if err := os.Symlink(p.dataDir, p.legacyDir); err != nil && !errors.Is(err, os.ErrExist) {
+		return err
+	}
+	_ = os.Remove(p.alpinePath(migratedMarker))
+	return nil
+}
+
+// moveTree renames src to dst, copying instead when they are on
+// different file systems. dst only appears once it is complete.
+func moveTree(src, dst string) error {
+	if os.Rename(src, dst) == nil {
+		return nil
+	}
+	partial := dst + ".migrating"
+	if err := os.RemoveAll(partial); err != nil {
+		return err
+	}
+	if err := copyTree(src, partial); err != nil {
+		return err
+	}
+	if err := os.Rename(partial, dst); err != nil {
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+// copyTree copies the files, directories and symlinks under src to dst.
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		}
+		return copyFile(path, target, info.Mode().Perm())
+	})
+}
+
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = in.Close()
+	}()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
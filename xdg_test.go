@@ -0,0 +1,202 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrateLegacyDir(t *testing.T) {
+	tests := []struct {
+		name     string
+		readOnly bool
+		// setup prepares the home directory, with data and legacy being
+		// the XDG data directory and ~/.alpineclient.
+		setup func(t *testing.T, p *Pinnacle)
+		// wantDir is the data directory in use afterwards, "data", "legacy"
+		// or "elsewhere" for ~/games/alpine.
+		wantDir     string
+		wantSymlink bool
+		// keepLink is set when setup links legacy somewhere the test
+		// doesn't check, which must be left alone.
+		keepLink  bool
+		wantFiles []string // relative to the data directory in use
+		wantState []string // relative to the state directory
+		wantErr   string
+	}{
+		{
+			name:        "fresh install",
+			setup:       func(*testing.T, *Pinnacle) {},
+			wantDir:     "data",
+			wantSymlink: true,
+		},
+		{
+			name:     "fresh install read-only",
+			readOnly: true,
+			setup:    func(*testing.T, *Pinnacle) {},
+			wantDir:  "data",
+		},
+		{
+			name: "legacy directory",
+			setup: func(t *testing.T, p *Pinnacle) {
+				writeTestFile(t, filepath.Join(p.legacyDir, "launcher.jar"), "jar")
+				writeTestFile(t, filepath.Join(p.legacyDir, "logs", "updater.log"), "log")
+				writeTestFile(t, filepath.Join(p.legacyDir, "state.json"), "{}")
+			},
+			wantDir:     "data",
+			wantSymlink: true,
+			wantFiles:   []string{"launcher.jar"},
+			wantState:   []string{"logs/updater.log", "state.json"},
+		},
+		{
+			name:     "legacy directory read-only",
+			readOnly: true,
+			setup: func(t *testing.T, p *Pinnacle) {
+				writeTestFile(t, filepath.Join(p.legacyDir, "launcher.jar"), "jar")
+			},
+			wantDir:   "legacy",
+			wantFiles: []string{"launcher.jar"},
+		},
+		{
+			name: "interrupted after copying",
+			setup: func(t *testing.T, p *Pinnacle) {
+				writeTestFile(t, filepath.Join(p.legacyDir, "launcher.jar"), "jar")
+				writeTestFile(t, p.alpinePath("launcher.jar"), "jar")
+				writeTestFile(t, p.alpinePath(migratedMarker), "")
+			},
+			wantDir:     "data",
+			wantSymlink: true,
+			wantFiles:   []string{"launcher.jar"},
+		},
+		{
+			name: "interrupted while copying",
+			setup: func(t *testing.T, p *Pinnacle) {
+				writeTestFile(t, filepath.Join(p.legacyDir, "launcher.jar"), "jar")
+				writeTestFile(t, filepath.Join(p.dataDir+".migrating", "launcher.jar"), "partial")
+			},
+			wantDir:     "data",
+			wantSymlink: true,
+			wantFiles:   []string{"launcher.jar"},
+		},
+		{
+			name: "interrupted before moving logs",
+			setup: func(t *testing.T, p *Pinnacle) {
+				writeTestFile(t, p.alpinePath("launcher.jar"), "jar")
+				writeTestFile(t, p.alpinePath("logs", "updater.log"), "log")
+				if err := os.Symlink(p.dataDir, p.legacyDir); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantDir:     "data",
+			wantSymlink: true,
+			wantFiles:   []string{"launcher.jar"},
+			wantState:   []string{"logs/updater.log"},
+		},
+		{
+			name: "symlink pointing elsewhere",
+			setup: func(t *testing.T, p *Pinnacle) {
+				elsewhere := filepath.Join(filepath.Dir(p.legacyDir), "games", "alpine")
+				writeTestFile(t, filepath.Join(elsewhere, "launcher.jar"), "jar")
+				writeTestFile(t, filepath.Join(elsewhere, "logs", "updater.log"), "log")
+				if err := os.Symlink(elsewhere, p.legacyDir); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantDir:     "elsewhere",
+			wantSymlink: true,
+			wantFiles:   []string{"launcher.jar", "logs/updater.log"},
+		},
+		{
+			name: "symlink to the data directory through another link",
+			setup: func(t *testing.T, p *Pinnacle) {
+				writeTestFile(t, p.alpinePath("launcher.jar"), "jar")
+				alias := filepath.Join(filepath.Dir(p.legacyDir), "alias")
+				if err := os.Symlink(p.dataDir, alias); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Symlink(alias, p.legacyDir); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantDir:   "data",
+			keepLink:  true,
+			wantFiles: []string{"launcher.jar"},
+		},
+		{
+			name: "dangling symlink",
+			setup: func(t *testing.T, p *Pinnacle) {
+				if err := os.Symlink(filepath.Join(filepath.Dir(p.legacyDir), "gone"), p.legacyDir); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantDir:  "data",
+			keepLink: true,
+			wantErr:  "unable to follow",
+		},
+		{
+			name: "both exist",
+			setup: func(t *testing.T, p *Pinnacle) {
+				writeTestFile(t, filepath.Join(p.legacyDir, "launcher.jar"), "jar")
+				writeTestFile(t, p.alpinePath("options.txt"), "")
+			},
+			wantDir:   "legacy",
+			wantFiles: []string{"launcher.jar"},
+			wantErr:   "both",
+		},
+		{
+			name: "legacy path is a file",
+			setup: func(t *testing.T, p *Pinnacle) {
+				writeTestFile(t, p.legacyDir, "")
+			},
+			wantDir: "data",
+			wantErr: "is not a directory",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			p := &Pinnacle{
+				dataDir:   filepath.Join(home, ".local", "share", "alpineclient"),
+				cacheDir:  filepath.Join(home, ".cache", "alpineclient"),
+				stateDir:  filepath.Join(home, ".local", "state", "alpineclient"),
+				legacyDir: filepath.Join(home, ".alpineclient"),
+			}
+			legacy, data, state := p.legacyDir, p.dataDir, p.stateDir
+			tt.setup(t, p)
+
+			checkErrContains(t, p.migrateLegacyDir(tt.readOnly), tt.wantErr)
+
+			elsewhere := filepath.Join(home, "games", "alpine")
+			want := map[string]string{"data": data, "legacy": legacy, "elsewhere": elsewhere}[tt.wantDir]
+			if p.dataDir != want {
+				t.Errorf("data directory = %s, want %s", p.dataDir, want)
+			}
+			if p.dataDir != data && (p.cacheDir != p.dataDir || p.stateDir != p.dataDir) {
+				t.Errorf("cache %s and state %s should follow the legacy directory", p.cacheDir, p.stateDir)
+			}
+			if got := isSymlink(legacy); got != (tt.wantSymlink || tt.keepLink) {
+				t.Errorf("%s is a symlink = %v, want %v", legacy, got, tt.wantSymlink)
+			}
+			if tt.wantSymlink {
+				if target, _ := os.Readlink(legacy); target != want {
+					t.Errorf("%s points to %s, want %s", legacy, target, want)
+				}
+			}
+			for _, name := range tt.wantFiles {
+				if !fileExists(p.alpinePath(name)) {
+					t.Errorf("missing %s in the data directory", name)
+				}
+			}
+			for _, name := range tt.wantState {
+				if !fileExists(filepath.Join(state, name)) {
+					t.Errorf("missing %s in the state directory", name)
+				}
+			}
+			for _, leftover := range []string{migratedMarker, filepath.Join("..", "alpineclient.migrating")} {
+				if tt.wantErr == "" && fileExists(filepath.Join(data, leftover)) {
+					t.Errorf("%s left behind", leftover)
+				}
+			}
+		})
+	}
+}